@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+const (
+	adaptiveFileName = "adaptive.json"
+	//adaptiveDifficultyName is the name under which the results of
+	//adaptive games are stored in the high scores and statistics.
+	adaptiveDifficultyName = "adaptive"
+
+	//adaptiveTargetWinRate is the share of games the player should win.
+	//The level settles where the player wins about this many games.
+	adaptiveTargetWinRate = 0.5
+	//adaptiveStep is the amount of levels a won game would raise the level
+	//by if the target win rate was 0.
+	adaptiveStep = 2.0
+	//adaptiveRecentGames is the amount of results remembered for showing
+	//the recent win rate.
+	adaptiveRecentGames = 10
+	//adaptiveLevelsPerBoard is the amount of levels played on the same
+	//board size before it grows. Within those levels, the hide time
+	//shrinks.
+	adaptiveLevelsPerBoard = 3
+)
+
+// adaptiveBoards are the board sizes used by the adaptive difficulty, from
+// the smallest to the largest one.
+var adaptiveBoards = []struct{ rowCount, columnCount int }{
+	{3, 2}, {3, 3}, {4, 3}, {4, 4}, {5, 4}, {5, 5},
+}
+
+// maxAdaptiveLevel is the highest level, which uses the largest board and
+// the shortest hide time.
+var maxAdaptiveLevel = len(adaptiveBoards) * adaptiveLevelsPerBoard
+
+// adaptiveTrainer tunes a difficulty to the player. After each game, the
+// level is raised or lowered depending on whether the game was won. As a
+// win raises the level by less than a loss lowers it when the target win
+// rate is above 50%, the level settles where the player wins the targeted
+// share of games.
+type adaptiveTrainer struct {
+	path string
+
+	//Level is kept as a fraction, so that the steps can be smaller than a
+	//whole level. The difficulty uses the rounded level.
+	Level float64 `json:"level"`
+	//RecentResults contains whether each of the latest games has been won,
+	//the latest one being last.
+	RecentResults []bool `json:"recentResults"`
+
+	//current is the difficulty of currentLevel. It's only replaced when
+	//the level changes, so that sessions keep a stable difficulty.
+	current      *engine.Difficulty
+	currentLevel int
+}
+
+// adaptivePath returns the location of the file containing the learned
+// level.
+func adaptivePath() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, adaptiveFileName), nil
+}
+
+// loadAdaptiveTrainer reads the progress stored at the given path. If no
+// file exists yet, the trainer starts at the first level. An empty path
+// results in a trainer that only lives in memory.
+func loadAdaptiveTrainer(path string) (*adaptiveTrainer, error) {
+	trainer := &adaptiveTrainer{
+		path:  path,
+		Level: 1,
+	}
+
+	if path != "" {
+		data, readError := ioutil.ReadFile(path)
+		if readError != nil && !os.IsNotExist(readError) {
+			return nil, readError
+		}
+		if readError == nil {
+			if parseError := json.Unmarshal(data, trainer); parseError != nil {
+				return nil, parseError
+			}
+		}
+	}
+
+	trainer.currentLevel = trainer.level()
+	trainer.current = adaptiveDifficulty(trainer.currentLevel)
+	return trainer, nil
+}
+
+// level returns the level currently played, which is between 1 and
+// maxAdaptiveLevel.
+func (trainer *adaptiveTrainer) level() int {
+	level := int(math.Round(trainer.Level))
+	if level < 1 {
+		return 1
+	}
+	if level > maxAdaptiveLevel {
+		return maxAdaptiveLevel
+	}
+	return level
+}
+
+// difficulty returns the difficulty matching the player's current level.
+func (trainer *adaptiveTrainer) difficulty() *engine.Difficulty {
+	return trainer.current
+}
+
+// add adjusts the level according to the result of the given session. The
+// session should already be over. Sessions that haven't been played on an
+// adaptive difficulty in classic mode are ignored, as their results don't
+// tell us anything about the level. The progress isn't saved
+// automatically.
+func (trainer *adaptiveTrainer) add(snapshot engine.Snapshot) {
+	if !isAdaptive(snapshot.Difficulty) || snapshot.Mode != engine.ClassicMode {
+		return
+	}
+
+	won := snapshot.State == engine.Victory
+	if won {
+		trainer.Level += adaptiveStep * (1 - adaptiveTargetWinRate)
+	} else {
+		trainer.Level -= adaptiveStep * adaptiveTargetWinRate
+	}
+	//The level mustn't leave the valid range, as it would take several
+	//games to get back otherwise.
+	trainer.Level = math.Max(1, math.Min(float64(maxAdaptiveLevel), trainer.Level))
+
+	trainer.RecentResults = append(trainer.RecentResults, won)
+	if len(trainer.RecentResults) > adaptiveRecentGames {
+		trainer.RecentResults = trainer.RecentResults[len(trainer.RecentResults)-adaptiveRecentGames:]
+	}
+
+	if trainer.level() != trainer.currentLevel {
+		trainer.currentLevel = trainer.level()
+		trainer.current = adaptiveDifficulty(trainer.currentLevel)
+	}
+}
+
+// description sums up the player's progress for the menu, e.g.
+// "Adaptive (level 4, won 3 of the last 6)".
+func (trainer *adaptiveTrainer) description() string {
+	if len(trainer.RecentResults) == 0 {
+		return fmt.Sprintf("Adaptive (level %d)", trainer.level())
+	}
+
+	var won int
+	for _, result := range trainer.RecentResults {
+		if result {
+			won++
+		}
+	}
+	return fmt.Sprintf("Adaptive (level %d, won %d of the last %d)",
+		trainer.level(), won, len(trainer.RecentResults))
+}
+
+// isAdaptive decides whether the given difficulty has been created by the
+// trainer. Custom difficulties can't use the same name.
+func isAdaptive(diff *engine.Difficulty) bool {
+	return diff.VisibleName == adaptiveDifficultyName
+}
+
+// adaptiveDifficulty creates the difficulty for the given level. Every few
+// levels, the board grows. The levels inbetween reduce the hide time. The
+// rune pool grows from digits over letters to both, so that the characters
+// get harder to tell apart as well.
+func adaptiveDifficulty(level int) *engine.Difficulty {
+	board := adaptiveBoards[(level-1)/adaptiveLevelsPerBoard]
+	cellCount := board.rowCount * board.columnCount
+	step := (level - 1) % adaptiveLevelsPerBoard
+
+	runePool := engine.RuneRange('0', '9')
+	if level > maxAdaptiveLevel/2 {
+		runePool = append(engine.RuneRange('0', '9'), engine.RuneRange('a', 'z')...)
+	} else if level > adaptiveLevelsPerBoard+1 {
+		runePool = engine.RuneRange('a', 'z')
+	}
+
+	return &engine.Difficulty{
+		VisibleName:             adaptiveDifficultyName,
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 3,
+		RowCount:                board.rowCount,
+		ColumnCount:             board.columnCount,
+		//Bigger boards take longer to memorize.
+		StartDelay:          500*time.Millisecond + time.Duration(cellCount)*100*time.Millisecond,
+		HideTimes:           1500*time.Millisecond - time.Duration(step)*250*time.Millisecond,
+		MaxHiddenRatio:      engine.DefaultMaxHiddenRatio,
+		MinimumWinningScore: engine.DefaultMinimumWinningScore,
+		RunePools:           [][]rune{runePool},
+	}
+}
+
+// save writes the progress to disk, creating the config directory if
+// required.
+func (trainer *adaptiveTrainer) save() error {
+	if trainer.path == "" {
+		return nil
+	}
+
+	data, marshalError := json.MarshalIndent(trainer, "", "  ")
+	if marshalError != nil {
+		return marshalError
+	}
+
+	if mkdirError := os.MkdirAll(filepath.Dir(trainer.path), 0755); mkdirError != nil {
+		return mkdirError
+	}
+
+	return ioutil.WriteFile(trainer.path, data, 0644)
+}
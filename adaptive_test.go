@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+func TestAdaptiveDifficulties(t *testing.T) {
+	previous := adaptiveDifficulty(1)
+	for level := 1; level <= maxAdaptiveLevel; level++ {
+		diff := adaptiveDifficulty(level)
+		if validationError := diff.Validate(); validationError != nil {
+			t.Errorf("level %d is invalid: %s", level, validationError)
+		}
+		if diff.RowCount*diff.ColumnCount < previous.RowCount*previous.ColumnCount {
+			t.Errorf("the board of level %d is smaller than the one of the level before", level)
+		}
+		previous = diff
+	}
+}
+
+func TestAdaptiveTrainer(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "nested", adaptiveFileName)
+	trainer, loadError := loadAdaptiveTrainer(path)
+	if loadError != nil {
+		t.Fatal(loadError)
+	}
+	if trainer.level() != 1 || trainer.description() != "Adaptive (level 1)" {
+		t.Errorf("unexpected initial progress: %s", trainer.description())
+	}
+
+	won := engine.Snapshot{State: engine.Victory, Mode: engine.ClassicMode, Difficulty: trainer.difficulty()}
+	lost := engine.Snapshot{State: engine.GameOver, Mode: engine.ClassicMode, Difficulty: trainer.difficulty()}
+
+	//Losing on the first level mustn't lower the level any further.
+	trainer.add(lost)
+	if trainer.Level != 1 {
+		t.Errorf("level dropped below 1: %f", trainer.Level)
+	}
+
+	trainer.add(won)
+	trainer.add(won)
+	if trainer.level() != 3 || trainer.difficulty().HideTimes >= adaptiveDifficulty(1).HideTimes {
+		t.Errorf("winning didn't make the game harder: %s", trainer.description())
+	}
+
+	//Games on other difficulties or modes don't count.
+	trainer.add(engine.Snapshot{State: engine.Victory, Mode: engine.ClassicMode, Difficulty: findDifficulty("easy")})
+	trainer.add(engine.Snapshot{State: engine.Victory, Mode: engine.EndlessMode, Difficulty: trainer.difficulty()})
+	if trainer.level() != 3 {
+		t.Errorf("unrelated games changed the level: %s", trainer.description())
+	}
+
+	trainer.add(lost)
+	if expected := "Adaptive (level 2, won 2 of the last 4)"; trainer.description() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, trainer.description())
+	}
+
+	if saveError := trainer.save(); saveError != nil {
+		t.Fatal(saveError)
+	}
+	reloaded, reloadError := loadAdaptiveTrainer(path)
+	if reloadError != nil {
+		t.Fatal(reloadError)
+	}
+	if reloaded.description() != trainer.description() {
+		t.Errorf("progress wasn't persisted: expected '%s', got '%s'", trainer.description(), reloaded.description())
+	}
+	if reloaded.difficulty().HideTimes != trainer.difficulty().HideTimes {
+		t.Error("the reloaded trainer uses a different difficulty")
+	}
+}
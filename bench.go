@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+// benchResult sums up all simulated games of a single difficulty.
+type benchResult struct {
+	difficulty string
+	games      int
+	won        int
+	//scores contains the final score of each game in ascending order.
+	scores []int
+}
+
+// benchDifficulty simulates the given amount of games. Each game uses its
+// own seed, starting at firstSeed, so that the results are reproducible.
+func benchDifficulty(diff *engine.Difficulty, mode engine.Mode, games int, firstSeed int64,
+	settings engine.BotSettings) (*benchResult, error) {
+	result := &benchResult{
+		difficulty: leaderboardName(diff.VisibleName, mode),
+		games:      games,
+		scores:     make([]int, 0, games),
+	}
+	for game := 0; game < games; game++ {
+		snapshot, simulationError := engine.Simulate(diff, mode, firstSeed+int64(game), settings)
+		if simulationError != nil {
+			return nil, simulationError
+		}
+		if snapshot.State == engine.Victory {
+			result.won++
+		}
+		result.scores = append(result.scores, snapshot.Score)
+	}
+	sort.Ints(result.scores)
+
+	return result, nil
+}
+
+// percentile returns the score that the given percentage of games didn't
+// exceed.
+func (result *benchResult) percentile(percent int) int {
+	return result.scores[(len(result.scores)-1)*percent/100]
+}
+
+// line formats the result as a row of the table printed by the bench
+// command.
+func (result *benchResult) line() string {
+	var sum int
+	for _, score := range result.scores {
+		sum += score
+	}
+	return fmt.Sprintf("%-24s %6d %8d%% %6d %6d %6d %6d %6d %6d", result.difficulty, result.games,
+		result.won*100/result.games, result.percentile(0), result.percentile(25), result.percentile(50),
+		result.percentile(75), result.percentile(100), sum/result.games)
+}
+
+// runBench implements the "bench" command, which lets a bot play each
+// difficulty many times and prints the win rates and score distributions.
+// All difficulties are benchmarked, unless names are passed.
+func runBench(arguments []string, target io.Writer) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	games := flags.Int("games", 1000, "amount of games simulated per difficulty")
+	modeName := flags.String("mode", engine.ClassicMode.String(), "game mode; either classic or endless")
+	seed := flags.Int64("seed", 1, "seed of the first game; each following game uses the next one")
+	memory := flags.Int("memory", 7, "amount of characters per board the bot can remember")
+	reactionTime := flags.Duration("reaction", 700*time.Millisecond, "time the bot takes to type a hidden character")
+	errorRate := flags.Float64("errors", 0.05, "probability of the bot pressing a wrong key, between 0 and 1")
+	flags.Parse(arguments)
+
+	usage := errors.New("usage: memoryalike bench [--games n] [--mode name] [--seed n] " +
+		"[--memory n] [--reaction duration] [--errors rate] [difficulty...]")
+	if *games <= 0 {
+		return usage
+	}
+	mode, modeError := engine.ParseMode(*modeName)
+	if modeError != nil {
+		return modeError
+	}
+	settings := engine.BotSettings{Memory: *memory, ReactionTime: *reactionTime, ErrorRate: *errorRate}
+	if validationError := settings.Validate(); validationError != nil {
+		return validationError
+	}
+
+	benched := difficulties
+	if flags.NArg() > 0 {
+		benched = make([]*engine.Difficulty, 0, flags.NArg())
+		for _, name := range flags.Args() {
+			diff := findDifficulty(name)
+			if diff == nil {
+				return fmt.Errorf("unknown difficulty '%s'", name)
+			}
+			benched = append(benched, diff)
+		}
+	}
+
+	header := fmt.Sprintf("%-24s %6s %9s %6s %6s %6s %6s %6s %6s",
+		"Difficulty", "Games", "Win rate", "Min", "25%", "Median", "75%", "Max", "Mean")
+	if _, writeError := fmt.Fprintln(target, header); writeError != nil {
+		return writeError
+	}
+	for _, diff := range benched {
+		result, benchError := benchDifficulty(diff, mode, *games, *seed, settings)
+		if benchError != nil {
+			return benchError
+		}
+		if _, writeError := fmt.Fprintln(target, result.line()); writeError != nil {
+			return writeError
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunBench(t *testing.T) {
+	var output bytes.Buffer
+	if benchError := runBench([]string{"-games", "50", "-errors", "0", "easy"}, &output); benchError != nil {
+		t.Fatal(benchError)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and one line, got '%s'", output.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "easy" || fields[1] != "50" || fields[2] != "100%" {
+		t.Errorf("unexpected result: %s", lines[1])
+	}
+
+	if benchError := runBench([]string{"unknown"}, &output); benchError == nil {
+		t.Error("an unknown difficulty was benchmarked")
+	}
+	if benchError := runBench([]string{"-mode", "pairs", "easy"}, &output); benchError == nil {
+		t.Error("pairs mode was benchmarked")
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const applicationName = "memoryalike"
+
+// configDirectory returns the directory in which all user specific files,
+// such as custom difficulties, are stored. The directory isn't guaranteed
+// to exist.
+func configDirectory() (string, error) {
+	userConfigDir, configDirError := os.UserConfigDir()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(userConfigDir, applicationName), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+const customDifficultiesFileName = "difficulties.json"
+
+// customDifficultiesPath returns the location of the file containing the
+// user defined difficulties.
+func customDifficultiesPath() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, customDifficultiesFileName), nil
+}
+
+// loadCustomDifficulties reads the difficulty definitions at the given path.
+// A non-existent file isn't an error, as custom difficulties are optional.
+// All invalid definitions are reported at once, so the user doesn't have to
+// fix their file one error at a time. The names of the definitions must not
+// clash with each other or with any of the existing difficulties.
+func loadCustomDifficulties(path string, existing []*engine.Difficulty) ([]*engine.Difficulty, error) {
+	data, readError := ioutil.ReadFile(path)
+	if readError != nil {
+		if os.IsNotExist(readError) {
+			return nil, nil
+		}
+		return nil, readError
+	}
+
+	var definitions []*engine.DifficultyDefinition
+	if parseError := json.Unmarshal(data, &definitions); parseError != nil {
+		return nil, fmt.Errorf("error parsing custom difficulties file '%s': %w", path, parseError)
+	}
+
+	//The adaptive difficulty isn't part of the existing ones, as it's
+	//created on the fly.
+	knownNames := map[string]bool{adaptiveDifficultyName: true}
+	for _, diff := range existing {
+		knownNames[diff.VisibleName] = true
+	}
+
+	var loaded []*engine.Difficulty
+	var problems []string
+	for index, definition := range definitions {
+		diff, conversionError := definition.ToDifficulty()
+		if conversionError == nil && knownNames[diff.VisibleName] {
+			conversionError = errors.New("a difficulty with this name already exists")
+		}
+
+		if conversionError != nil {
+			problems = append(problems, fmt.Sprintf("\tdifficulty #%d (%q): %s",
+				index+1, definition.VisibleName, conversionError))
+			continue
+		}
+
+		knownNames[diff.VisibleName] = true
+		loaded = append(loaded, diff)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid custom difficulties in '%s':\n%s", path, strings.Join(problems, "\n"))
+	}
+
+	return loaded, nil
+}
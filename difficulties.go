@@ -1,79 +1,19 @@
 package main
 
-import "time"
+import "github.com/Bios-Marcel/memoryalike/engine"
 
-type difficulty struct {
-	visibleName string
+// difficulties contains all difficulties that can be chosen in the menu.
+// Custom difficulties are appended to the built-in ones on startup.
+var difficulties = engine.BuiltInDifficulties()
 
-	startDelay time.Duration
-	hideTimes  time.Duration
+// findDifficulty returns the difficulty with the given visible name or nil
+// if there's no such difficulty.
+func findDifficulty(visibleName string) *engine.Difficulty {
+	for _, diff := range difficulties {
+		if diff.VisibleName == visibleName {
+			return diff
+		}
+	}
 
-	correctGuessPoints      int
-	invalidKeyPressPenality int
-
-	rowCount    int
-	columnCount int
-	runePools   [][]rune
-}
-
-var difficulties = []*difficulty{
-	{
-		visibleName:        "easy",
-		correctGuessPoints: 5,
-		//You better take easy seriously!
-		invalidKeyPressPenality: 4,
-		rowCount:                3,
-		columnCount:             2,
-		startDelay:              750 * time.Millisecond,
-		hideTimes:               1250 * time.Millisecond,
-		runePools: [][]rune{
-			runeRange('1', '6'),
-		},
-	}, {
-		visibleName:             "normal",
-		correctGuessPoints:      5,
-		invalidKeyPressPenality: 2,
-		rowCount:                3,
-		columnCount:             3,
-		startDelay:              1500 * time.Millisecond,
-		hideTimes:               1250 * time.Millisecond,
-		runePools: [][]rune{
-			runeRange('0', '9'),
-		},
-	}, {
-		visibleName:             "hard",
-		correctGuessPoints:      5,
-		invalidKeyPressPenality: 5,
-		rowCount:                3,
-		columnCount:             3,
-		startDelay:              1500 * time.Millisecond,
-		hideTimes:               1500 * time.Millisecond,
-		runePools: [][]rune{
-			runeRange('a', 'z'),
-		},
-	}, {
-		visibleName:             "extreme",
-		correctGuessPoints:      4,
-		invalidKeyPressPenality: 5,
-		rowCount:                4,
-		columnCount:             3,
-		startDelay:              1500 * time.Millisecond,
-		hideTimes:               1500 * time.Millisecond,
-		runePools: [][]rune{
-			runeRange('0', '9'),
-			runeRange('a', 'z'),
-		},
-	}, {
-		visibleName:             "nightmare",
-		correctGuessPoints:      4,
-		invalidKeyPressPenality: 10,
-		rowCount:                5,
-		columnCount:             5,
-		startDelay:              2500 * time.Millisecond,
-		hideTimes:               1500 * time.Millisecond,
-		runePools: [][]rune{
-			runeRange('0', '9'),
-			runeRange('a', 'z'),
-		},
-	},
+	return nil
 }
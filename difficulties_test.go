@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+func TestLoadCustomDifficulties(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	writeDefinitions := func(t *testing.T, content string) string {
+		path := filepath.Join(tempDir, customDifficultiesFileName)
+		if writeError := ioutil.WriteFile(path, []byte(content), 0600); writeError != nil {
+			t.Fatal(writeError)
+		}
+		return path
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		loaded, loadError := loadCustomDifficulties(filepath.Join(tempDir, "nope.json"), difficulties)
+		if loadError != nil || loaded != nil {
+			t.Errorf("expected nothing to be loaded, got %v, %v", loaded, loadError)
+		}
+	})
+
+	t.Run("valid definition", func(t *testing.T) {
+		path := writeDefinitions(t, `[{
+			"name": "team",
+			"startDelay": "1s",
+			"hideTimes": "900ms",
+			"points": 3,
+			"penalty": 1,
+			"rowCount": 4,
+			"columnCount": 6,
+			"runePools": ["a-z", "0123"]
+		}]`)
+		loaded, loadError := loadCustomDifficulties(path, difficulties)
+		if loadError != nil {
+			t.Fatal(loadError)
+		}
+		if len(loaded) != 1 {
+			t.Fatalf("expected one difficulty, got %d", len(loaded))
+		}
+
+		diff := loaded[0]
+		if diff.VisibleName != "team" || diff.HideTimes != 900*time.Millisecond ||
+			diff.RowCount != 4 || diff.ColumnCount != 6 {
+			t.Errorf("unexpected difficulty: %+v", diff)
+		}
+		if len(diff.RunePools) != 2 || len(diff.RunePools[0]) != 26 || len(diff.RunePools[1]) != 4 {
+			t.Errorf("unexpected rune pools: %v", diff.RunePools)
+		}
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		path := writeDefinitions(t, `[
+			{"name": "default", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 2, "columnCount": 2, "runePools": ["0-9"]},
+			{"name": "brutal", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 2, "columnCount": 2, "runePools": ["0-9"], "maxHiddenCount": 2},
+			{"name": "practice", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 2, "columnCount": 2, "runePools": ["0-9"],
+			 "maxHiddenRatio": 0, "minimumWinningScore": -1000},
+			{"name": "speedy", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 2, "columnCount": 2, "runePools": ["0-9"],
+			 "speedBonus": 4, "speedBonusHalfLife": "750ms"}
+		]`)
+		loaded, loadError := loadCustomDifficulties(path, difficulties)
+		if loadError != nil {
+			t.Fatal(loadError)
+		}
+
+		for index, expected := range []engine.Difficulty{
+			{MaxHiddenRatio: engine.DefaultMaxHiddenRatio, MinimumWinningScore: engine.DefaultMinimumWinningScore},
+			{MaxHiddenCount: 2, MinimumWinningScore: engine.DefaultMinimumWinningScore},
+			{MinimumWinningScore: -1000},
+		} {
+			diff := loaded[index]
+			if diff.MaxHiddenRatio != expected.MaxHiddenRatio || diff.MaxHiddenCount != expected.MaxHiddenCount ||
+				diff.MinimumWinningScore != expected.MinimumWinningScore {
+				t.Errorf("unexpected rules for %s: ratio %g, count %d, minimum score %d", diff.VisibleName,
+					diff.MaxHiddenRatio, diff.MaxHiddenCount, diff.MinimumWinningScore)
+			}
+		}
+		if speedy := loaded[3]; speedy.SpeedBonus != 4 || speedy.SpeedBonusHalfLife != 750*time.Millisecond {
+			t.Errorf("unexpected speed bonus: %d, half-life %s", speedy.SpeedBonus, speedy.SpeedBonusHalfLife)
+		}
+	})
+
+	t.Run("invalid definitions", func(t *testing.T) {
+		path := writeDefinitions(t, `[
+			{"name": "small", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 4, "columnCount": 6, "runePools": ["0-9"]},
+			{"name": "normal", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 1, "columnCount": 1, "runePools": ["0-9"]},
+			{"name": "slow", "startDelay": "forever", "hideTimes": "1s", "points": 1,
+			 "rowCount": 1, "columnCount": 1, "runePools": ["0-9"]},
+			{"name": "odd", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 1, "columnCount": 1, "runePools": ["0-9"], "maxHiddenRatio": 1.5},
+			{"name": "fast", "startDelay": "1s", "hideTimes": "1s", "points": 1,
+			 "rowCount": 1, "columnCount": 1, "runePools": ["0-9"], "speedBonus": 5}
+		]`)
+		loaded, loadError := loadCustomDifficulties(path, difficulties)
+		if loadError == nil {
+			t.Fatalf("expected an error, got %v", loaded)
+		}
+
+		for _, expected := range []string{
+			`#1 ("small"): the rune pools contain 10 characters, but a 4x6 board needs at least 24`,
+			`#2 ("normal"): a difficulty with this name already exists`,
+			`#3 ("slow"): invalid start delay`,
+			`#4 ("odd"): the maximum hidden ratio must be between 0 and 1; got 1.5`,
+			`#5 ("fast"): the half-life of the speed bonus must be greater than 0; got 0s`,
+		} {
+			if !strings.Contains(loadError.Error(), expected) {
+				t.Errorf("error '%s' doesn't mention '%s'", loadError, expected)
+			}
+		}
+	})
+}
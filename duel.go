@@ -0,0 +1,476 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	//duelProtocolVersion has to be increased whenever the messages change
+	//in an incompatible way, as both players need the same version.
+	duelProtocolVersion = 1
+	//defaultDuelAddress is the address the host listens on if none has
+	//been passed.
+	defaultDuelAddress = ":7777"
+	//duelStartTimeout is the time the joining player waits for the host
+	//to start the duel after connecting.
+	duelStartTimeout = 10 * time.Second
+)
+
+type duelMessageKind string
+
+const (
+	//startMessage is sent by the host once a player has joined. It
+	//contains everything required for creating the same board.
+	startMessage duelMessageKind = "start"
+	//hideMessage is sent by the host whenever the next rune has to be
+	//hidden, as the host drives the hide schedule of both players.
+	hideMessage duelMessageKind = "hide"
+	//progressMessage is sent by both players whenever their session has
+	//changed.
+	progressMessage duelMessageKind = "progress"
+)
+
+// duelMessage is a single message exchanged between the players. Only the
+// fields relevant for the kind of message are set. Messages are sent as
+// JSON, one per line.
+type duelMessage struct {
+	Kind duelMessageKind `json:"kind"`
+
+	//Version, Seed and Difficulty are only set for start messages.
+	Version    int                          `json:"version,omitempty"`
+	Seed       int64                        `json:"seed,omitempty"`
+	Difficulty *engine.DifficultyDefinition `json:"difficulty,omitempty"`
+
+	Progress *duelProgress `json:"progress,omitempty"`
+}
+
+// duelProgress is what a player gets to know about the opponent's game.
+type duelProgress struct {
+	Guessed           int    `json:"guessed"`
+	InvalidKeyPresses int    `json:"invalidKeyPresses"`
+	Score             int    `json:"score"`
+	State             string `json:"state"`
+}
+
+// newDuelProgress sums up the given session for the opponent.
+func newDuelProgress(snapshot engine.Snapshot) duelProgress {
+	var guessed int
+	for _, cell := range snapshot.Board {
+		if cell.State == engine.Guessed {
+			guessed++
+		}
+	}
+
+	return duelProgress{
+		Guessed:           guessed,
+		InvalidKeyPresses: snapshot.InvalidKeyPresses,
+		Score:             snapshot.Score,
+		State:             snapshot.State.String(),
+	}
+}
+
+// duelStatus is what's known about the opponent of a duel. It's shown next
+// to the board and on the end screen.
+type duelStatus struct {
+	//opponent is nil until the opponent has sent their first progress.
+	opponent *duelProgress
+	//connectionError is set once the connection has been lost.
+	connectionError error
+}
+
+// outcome compares the finished session to the opponent's result. A
+// victory beats a loss, otherwise the higher score wins. If the opponent
+// hasn't finished yet, an empty string is returned.
+func (status *duelStatus) outcome(snapshot engine.Snapshot) string {
+	if status.opponent == nil || status.opponent.State == engine.Ongoing.String() {
+		return ""
+	}
+
+	won := snapshot.State == engine.Victory
+	opponentWon := status.opponent.State == engine.Victory.String()
+	if won == opponentWon {
+		if snapshot.Score > status.opponent.Score {
+			won, opponentWon = true, false
+		} else if snapshot.Score < status.opponent.Score {
+			won, opponentWon = false, true
+		}
+	}
+
+	if won && !opponentWon {
+		return "You have won the duel!"
+	}
+	if opponentWon && !won {
+		return "Your opponent has won the duel."
+	}
+	return "The duel is a draw."
+}
+
+// duelConnection exchanges messages with the opponent. Incoming messages
+// are read on a separate goroutine, so that they can be awaited alongside
+// other events.
+type duelConnection struct {
+	conn    net.Conn
+	encoder *json.Encoder
+	//incoming is closed once the connection has been lost.
+	incoming chan duelMessage
+	//readError is the reason for incoming being closed. It must only be
+	//read once incoming has been closed.
+	readError error
+	//done is closed by Close, so that reading doesn't block on incoming
+	//once nobody receives from it anymore.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newDuelConnection(conn net.Conn) *duelConnection {
+	connection := &duelConnection{
+		conn:     conn,
+		encoder:  json.NewEncoder(conn),
+		incoming: make(chan duelMessage),
+		done:     make(chan struct{}),
+	}
+	go connection.read()
+	return connection
+}
+
+func (connection *duelConnection) read() {
+	defer close(connection.incoming)
+
+	decoder := json.NewDecoder(connection.conn)
+	for {
+		var message duelMessage
+		if decodeError := decoder.Decode(&message); decodeError != nil {
+			connection.readError = decodeError
+			return
+		}
+
+		select {
+		case connection.incoming <- message:
+		case <-connection.done:
+			connection.readError = errors.New("the connection has been closed")
+			return
+		}
+	}
+}
+
+// send writes a single message to the opponent.
+func (connection *duelConnection) send(message duelMessage) error {
+	return connection.encoder.Encode(message)
+}
+
+// Close closes the underlying connection, which causes incoming to be
+// closed as well. It can be called more than once.
+func (connection *duelConnection) Close() error {
+	connection.closeOnce.Do(func() {
+		close(connection.done)
+	})
+	return connection.conn.Close()
+}
+
+// hostDuel waits for a single player to join via the given listener and
+// starts the duel by telling them which board to play.
+func hostDuel(listener net.Listener, diff *engine.Difficulty, seed int64) (*duelConnection, error) {
+	conn, acceptError := listener.Accept()
+	if acceptError != nil {
+		return nil, acceptError
+	}
+
+	connection := newDuelConnection(conn)
+	start := duelMessage{
+		Kind:       startMessage,
+		Version:    duelProtocolVersion,
+		Seed:       seed,
+		Difficulty: engine.NewDifficultyDefinition(diff),
+	}
+	if sendError := connection.send(start); sendError != nil {
+		connection.Close()
+		return nil, sendError
+	}
+
+	return connection, nil
+}
+
+// joinDuel connects to the host at the given address and waits for the
+// duel to start. The difficulty is sent by the host, so custom
+// difficulties don't have to be shared beforehand.
+func joinDuel(address string) (*duelConnection, *engine.Difficulty, int64, error) {
+	conn, dialError := net.DialTimeout("tcp", address, duelStartTimeout)
+	if dialError != nil {
+		return nil, nil, 0, dialError
+	}
+
+	connection := newDuelConnection(conn)
+	fail := func(failure error) (*duelConnection, *engine.Difficulty, int64, error) {
+		connection.Close()
+		return nil, nil, 0, failure
+	}
+
+	var start duelMessage
+	select {
+	case message, open := <-connection.incoming:
+		if !open {
+			return fail(fmt.Errorf("the host has closed the connection: %w", connection.readError))
+		}
+		start = message
+	case <-time.After(duelStartTimeout):
+		return fail(errors.New("the host hasn't started the duel in time"))
+	}
+
+	if start.Kind != startMessage || start.Difficulty == nil {
+		return fail(fmt.Errorf("expected the host to start the duel, got '%s'", start.Kind))
+	}
+	if start.Version != duelProtocolVersion {
+		return fail(fmt.Errorf("the host uses version %d of the duel protocol, but this game uses version %d",
+			start.Version, duelProtocolVersion))
+	}
+	diff, difficultyError := start.Difficulty.ToDifficulty()
+	if difficultyError != nil {
+		return fail(fmt.Errorf("the host has sent an invalid difficulty: %w", difficultyError))
+	}
+
+	return connection, diff, start.Seed, nil
+}
+
+// duel is a head-to-head game against a player on another machine. Both
+// players play the same board in classic mode, while the host decides when
+// the runes are hidden. Just like gameLoop, everything is handled on the
+// goroutine calling run.
+type duel struct {
+	screen     tcell.Screen
+	events     <-chan tcell.Event
+	renderer   *renderer
+	connection *duelConnection
+	//host decides whether this side drives the hide schedule.
+	host bool
+
+	session *engine.Session
+	//updates is nil once the session has ended.
+	updates <-chan struct{}
+	end     *endScreen
+	//sentProgress is the progress the opponent knows about.
+	sentProgress duelProgress
+}
+
+// newDuel creates the session for the given board. Runes are only hidden
+// once run is called.
+func newDuel(screen tcell.Screen, renderer *renderer, connection *duelConnection, host bool,
+	diff *engine.Difficulty, seed int64) (*duel, error) {
+	//The clock is only used for measuring time, as the session's runes
+	//are hidden via Tick.
+	session, sessionError := engine.NewSession(diff, engine.ClassicMode, seed, engine.WallClock{})
+	if sessionError != nil {
+		return nil, sessionError
+	}
+	return &duel{
+		screen:     screen,
+		events:     pollEvents(screen),
+		renderer:   renderer,
+		connection: connection,
+		host:       host,
+		session:    session,
+		updates:    session.Subscribe(),
+		end:        &endScreen{duel: &duelStatus{}},
+	}, nil
+}
+
+// run plays the duel until the player quits. The game isn't paused if the
+// terminal is too small, as the opponent's runes are hidden at the same
+// time.
+func (d *duel) run() {
+	var hideTimer <-chan time.Time
+	if d.host {
+		diff := d.session.Snapshot().Difficulty
+		hideTimer = time.After(diff.StartDelay + diff.HideTimes)
+	}
+
+	statusLineTicker := time.NewTicker(time.Second)
+	defer statusLineTicker.Stop()
+	incoming := d.connection.incoming
+	for {
+		d.sendProgress()
+		d.renderer.drawGameBoard(d.screen, d.session.Snapshot(), d.end)
+
+		select {
+		case event, open := <-d.events:
+			if !open {
+				return
+			}
+			if quit := d.handleEvent(event); quit {
+				return
+			}
+		case message, open := <-incoming:
+			if !open {
+				d.end.duel.connectionError = d.connection.readError
+				incoming = nil
+				continue
+			}
+			d.handleMessage(message)
+		case _, open := <-d.updates:
+			if !open {
+				d.updates = nil
+			}
+		case <-hideTimer:
+			//The opponent is told first, so that both runes are hidden at
+			//about the same time.
+			d.send(duelMessage{Kind: hideMessage})
+			d.session.Tick()
+			hideTimer = time.After(d.session.Snapshot().Difficulty.HideTimes)
+		case <-statusLineTicker.C:
+		}
+	}
+}
+
+// handleEvent reacts to a single event of the screen. If the player wants
+// to quit, true is returned.
+func (d *duel) handleEvent(screenEvent tcell.Event) bool {
+	switch event := screenEvent.(type) {
+	case *tcell.EventKey:
+		keys := d.renderer.keys
+		if keys.is(event, quitAction) {
+			return true
+		} else if keys.is(event, surrenderAction) {
+			//Hitting ESC again on the end screen leaves the duel.
+			if d.session.Snapshot().State != engine.Ongoing {
+				return true
+			}
+			d.session.Surrender()
+		} else if event.Key() == tcell.KeyRune {
+			d.session.PressRune(event.Rune())
+		}
+	case *tcell.EventResize:
+		d.screen.Clear()
+	default:
+		//Unsupported or irrelevant event
+	}
+
+	return false
+}
+
+// handleMessage applies a message sent by the opponent.
+func (d *duel) handleMessage(message duelMessage) {
+	switch message.Kind {
+	case hideMessage:
+		//Only the host may decide when runes are hidden.
+		if !d.host {
+			d.session.Tick()
+		}
+	case progressMessage:
+		if message.Progress != nil {
+			d.end.duel.opponent = message.Progress
+		}
+	default:
+		//Unknown messages might come from a newer version and are ignored.
+	}
+}
+
+// sendProgress tells the opponent about the session, if it has changed
+// since the last time.
+func (d *duel) sendProgress() {
+	progress := newDuelProgress(d.session.Snapshot())
+	if progress == d.sentProgress {
+		return
+	}
+
+	d.sentProgress = progress
+	d.send(duelMessage{Kind: progressMessage, Progress: &progress})
+}
+
+// send writes the message to the opponent. Errors aren't returned, as the
+// player can continue their game without the opponent. Instead, the
+// connection is closed, which is then shown in the opponent panel.
+func (d *duel) send(message duelMessage) {
+	if d.end.duel.connectionError != nil {
+		return
+	}
+	if sendError := d.connection.send(message); sendError != nil {
+		d.connection.Close()
+	}
+}
+
+// runHost implements the "host" command. It waits for another player to
+// join and then starts a duel on the chosen difficulty.
+func runHost(arguments []string, renderer *renderer) error {
+	flags := flag.NewFlagSet("host", flag.ExitOnError)
+	difficultyName := flags.String("difficulty", "normal", "name of the difficulty that is played")
+	seed := flags.Int64("seed", 0, "seed used for generating the board; random by default")
+	flags.Parse(arguments)
+
+	if flags.NArg() > 1 {
+		return errors.New("usage: memoryalike host [--difficulty name] [--seed n] [address]")
+	}
+	address := defaultDuelAddress
+	if flags.NArg() == 1 {
+		address = flags.Arg(0)
+	}
+
+	diff := findDifficulty(*difficultyName)
+	if diff == nil {
+		return fmt.Errorf("unknown difficulty '%s'", *difficultyName)
+	}
+	if conflictError := renderer.keys.checkDifficulty(diff, engine.ClassicMode); conflictError != nil {
+		return conflictError
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	listener, listenError := net.Listen("tcp", address)
+	if listenError != nil {
+		return listenError
+	}
+	defer listener.Close()
+
+	fmt.Printf("Waiting for an opponent to join via 'memoryalike join %s' ...\n", listener.Addr())
+	connection, hostError := hostDuel(listener, diff, *seed)
+	if hostError != nil {
+		return hostError
+	}
+	defer connection.Close()
+
+	return playDuel(renderer, connection, true, diff, *seed)
+}
+
+// runJoin implements the "join" command, which connects to a player that
+// is hosting a duel.
+func runJoin(arguments []string, renderer *renderer) error {
+	if len(arguments) != 1 {
+		return errors.New("usage: memoryalike join host:port")
+	}
+
+	connection, diff, seed, joinError := joinDuel(arguments[0])
+	if joinError != nil {
+		return joinError
+	}
+	defer connection.Close()
+
+	if conflictError := renderer.keys.checkDifficulty(diff, engine.ClassicMode); conflictError != nil {
+		return conflictError
+	}
+
+	return playDuel(renderer, connection, false, diff, seed)
+}
+
+// playDuel creates the screen and blocks until the player leaves the duel.
+func playDuel(renderer *renderer, connection *duelConnection, host bool, diff *engine.Difficulty, seed int64) error {
+	screen, screenCreationError := createScreen(false)
+	if screenCreationError != nil {
+		return screenCreationError
+	}
+	defer screen.Fini()
+
+	d, duelError := newDuel(screen, renderer, connection, host, diff, seed)
+	if duelError != nil {
+		return duelError
+	}
+	d.run()
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestDuelOnLocalhost starts a duel between two players on the same
+// machine and makes sure that both play the same board and that the host
+// decides when runes are hidden.
+func TestDuelOnLocalhost(t *testing.T) {
+	listener, listenError := net.Listen("tcp", "127.0.0.1:0")
+	if listenError != nil {
+		t.Fatal(listenError)
+	}
+	defer listener.Close()
+
+	hostDifficulty := findDifficulty("hard")
+	type hostResult struct {
+		connection *duelConnection
+		err        error
+	}
+	hosted := make(chan hostResult)
+	go func() {
+		connection, hostError := hostDuel(listener, hostDifficulty, 42)
+		hosted <- hostResult{connection, hostError}
+	}()
+
+	guestConnection, guestDifficulty, seed, joinError := joinDuel(listener.Addr().String())
+	if joinError != nil {
+		t.Fatal(joinError)
+	}
+	defer guestConnection.Close()
+	host := <-hosted
+	if host.err != nil {
+		t.Fatal(host.err)
+	}
+	defer host.connection.Close()
+
+	if seed != 42 || guestDifficulty.VisibleName != "hard" || guestDifficulty.HideTimes != hostDifficulty.HideTimes {
+		t.Fatalf("the guest received seed %d and difficulty %+v", seed, guestDifficulty)
+	}
+
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	renderer := newRenderer(defaultKeymap(), darkTheme)
+	hostSide, hostError := newDuel(screen, renderer, host.connection, true, hostDifficulty, 42)
+	if hostError != nil {
+		t.Fatal(hostError)
+	}
+	guestSide, guestError := newDuel(screen, renderer, guestConnection, false, guestDifficulty, seed)
+	if guestError != nil {
+		t.Fatal(guestError)
+	}
+
+	hostBoard, guestBoard := hostSide.session.Snapshot().Board, guestSide.session.Snapshot().Board
+	for index := range hostBoard {
+		if hostBoard[index].Character != guestBoard[index].Character {
+			t.Fatalf("the boards differ at cell %d", index)
+		}
+	}
+
+	receive := func(connection *duelConnection) duelMessage {
+		select {
+		case message, open := <-connection.incoming:
+			if !open {
+				t.Fatalf("connection closed: %s", connection.readError)
+			}
+			return message
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+		return duelMessage{}
+	}
+
+	//Hides sent by the host are applied by the guest, but a guest can't
+	//hide runes of the host.
+	hostSide.send(duelMessage{Kind: hideMessage})
+	guestSide.handleMessage(receive(guestConnection))
+	if hidden := guestSide.session.Snapshot().HiddenCellCount; hidden != 1 {
+		t.Errorf("expected the guest to have 1 hidden cell, got %d", hidden)
+	}
+	guestSide.send(duelMessage{Kind: hideMessage})
+	hostSide.handleMessage(receive(host.connection))
+	if hidden := hostSide.session.Snapshot().HiddenCellCount; hidden != 0 {
+		t.Errorf("the guest has hidden a cell of the host")
+	}
+
+	guestSide.session.PressRune('!')
+	guestSide.sendProgress()
+	hostSide.handleMessage(receive(host.connection))
+	if opponent := hostSide.end.duel.opponent; opponent == nil || opponent.InvalidKeyPresses != 1 {
+		t.Errorf("the host doesn't know about the guest's mistake: %+v", opponent)
+	}
+}
+
+func TestDuelOutcome(t *testing.T) {
+	won := engine.Snapshot{State: engine.Victory, Score: 10}
+	lost := engine.Snapshot{State: engine.GameOver, Score: 30}
+	tests := []struct {
+		name     string
+		snapshot engine.Snapshot
+		opponent *duelProgress
+		expected string
+	}{
+		{"no progress", won, nil, ""},
+		{"opponent ongoing", won, &duelProgress{State: "ongoing"}, ""},
+		{"victory beats score", won, &duelProgress{State: "game over", Score: 50}, "You have won the duel!"},
+		{"loss against victory", lost, &duelProgress{State: "victory", Score: 5}, "Your opponent has won the duel."},
+		{"higher score", lost, &duelProgress{State: "game over", Score: 20}, "You have won the duel!"},
+		{"same score", won, &duelProgress{State: "victory", Score: 10}, "The duel is a draw."},
+	}
+	for _, test := range tests {
+		status := &duelStatus{opponent: test.opponent}
+		if outcome := status.outcome(test.snapshot); outcome != test.expected {
+			t.Errorf("%s: expected '%s', got '%s'", test.name, test.expected, outcome)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// MaxSimulatedDuration is the playing time after which a simulated game is
+// surrendered. Endless games could run forever if the bot is faster than
+// the shortest hide time.
+const MaxSimulatedDuration = time.Hour
+
+// BotSettings describe the abilities of a simulated player.
+type BotSettings struct {
+	//Memory is the amount of characters of a board the bot can remember.
+	//Hidden characters it doesn't remember have to be guessed.
+	Memory int
+	//ReactionTime is the time it takes the bot to type a character once
+	//it has noticed a hidden cell.
+	ReactionTime time.Duration
+	//ErrorRate is the probability of pressing a wrong key instead of the
+	//one the bot meant to press, between 0 and 1.
+	ErrorRate float64
+}
+
+// Validate checks whether a bot can play using these settings.
+func (settings BotSettings) Validate() error {
+	if settings.Memory < 0 {
+		return fmt.Errorf("the memory must not be negative; got %d", settings.Memory)
+	}
+
+	if settings.ReactionTime <= 0 {
+		return fmt.Errorf("the reaction time must be greater than 0; got %s", settings.ReactionTime)
+	}
+
+	if settings.ErrorRate < 0 || settings.ErrorRate > 1 {
+		return fmt.Errorf("the error rate must be between 0 and 1; got %g", settings.ErrorRate)
+	}
+
+	return nil
+}
+
+// bot plays a single session. It only knows what a player could see on
+// the screen, plus the characters it has memorized before they were
+// hidden.
+type bot struct {
+	settings BotSettings
+	random   *rand.Rand
+	session  *Session
+	clock    *ManualClock
+	start    time.Time
+
+	//remembered contains the characters of the current board the bot has
+	//memorized.
+	remembered map[rune]bool
+	//ruledOut contains the characters the bot has guessed wrongly during
+	//the current round, so it doesn't guess them again.
+	ruledOut map[rune]bool
+	//round is the round the bot has memorized the board of.
+	round int
+	//nextHide is the offset from the start at which the session hides
+	//the next rune. It follows the same schedule as Session.Start.
+	nextHide time.Duration
+	//presses contains the offsets at which the bot will press a key, one
+	//per cell it has noticed being hidden, in ascending order.
+	presses []time.Duration
+}
+
+// Simulate plays a whole game using the given bot settings and returns the
+// final state of the session. Time is simulated, so a game only takes as
+// long as it takes to compute it. Two simulations using the same
+// arguments have the same result. Only classic and endless games can be
+// simulated.
+func Simulate(difficulty *Difficulty, mode Mode, seed int64, settings BotSettings) (Snapshot, error) {
+	if mode != ClassicMode && mode != EndlessMode {
+		return Snapshot{}, errors.New("the bot can only play classic and endless games")
+	}
+	if validationError := settings.Validate(); validationError != nil {
+		return Snapshot{}, validationError
+	}
+
+	clock := NewManualClock(time.Time{})
+	session, sessionError := NewSession(difficulty, mode, seed, clock)
+	if sessionError != nil {
+		return Snapshot{}, sessionError
+	}
+	player := &bot{
+		settings: settings,
+		//The bot's decisions mustn't influence the board, therefore it
+		//uses its own source of randomness.
+		random:   rand.New(rand.NewSource(seed)),
+		session:  session,
+		clock:    clock,
+		start:    clock.Now(),
+		nextHide: difficulty.StartDelay + difficulty.HideTimes,
+	}
+	player.memorize()
+	return player.play(), nil
+}
+
+// play advances the clock from one event to the next, until the session
+// is over.
+func (b *bot) play() Snapshot {
+	for {
+		snapshot := b.session.Snapshot()
+		if snapshot.State != Ongoing {
+			return snapshot
+		}
+		//Without anything left to hide or type, the game can't progress
+		//anymore, so the bot gives up.
+		if snapshot.Duration >= MaxSimulatedDuration ||
+			(len(b.presses) == 0 && snapshot.CellsLeftToHide == 0) {
+			b.session.Surrender()
+			continue
+		}
+
+		//Hides happen before presses scheduled for the same time, so that
+		//those presses already see the hidden cell.
+		if len(b.presses) == 0 || b.nextHide <= b.presses[0] {
+			hiddenAt := b.nextHide
+			b.advanceTo(hiddenAt)
+			b.session.Tick()
+			b.nextHide += snapshot.Difficulty.HideTimes
+			//Once all cells of a board have been hidden, ticking doesn't
+			//hide anything, so there's nothing to notice.
+			if b.session.Snapshot().CellsLeftToHide < snapshot.CellsLeftToHide {
+				b.presses = insertSorted(b.presses, hiddenAt+b.settings.ReactionTime)
+			}
+		} else {
+			b.advanceTo(b.presses[0])
+			b.presses = b.presses[1:]
+			b.press(snapshot)
+		}
+
+		//A new endless round shows a new board, which has to be memorized
+		//again. Cells noticed in the previous round don't matter anymore.
+		if round := b.session.Snapshot().Round; round != b.round {
+			b.memorize()
+			b.presses = nil
+			b.nextHide = b.elapsed() + b.session.Snapshot().Difficulty.HideTimes
+		}
+	}
+}
+
+// press types the character of a hidden cell. If the bot doesn't remember
+// any of the hidden characters, it has to guess. Wrong presses are retried
+// after another reaction time, until all hidden cells have been guessed.
+func (b *bot) press(snapshot Snapshot) {
+	var known []rune
+	visible := make(map[rune]bool)
+	for _, cell := range snapshot.Board {
+		if cell.State != Hidden {
+			visible[cell.Character] = true
+		} else if b.remembered[cell.Character] {
+			known = append(known, cell.Character)
+		}
+	}
+	if snapshot.HiddenCellCount == 0 {
+		return
+	}
+
+	var pressed rune
+	if len(known) > 0 {
+		pressed = known[b.random.Intn(len(known))]
+	} else {
+		//Anything that isn't visible could be hidden, except for the
+		//characters that the bot knows are somewhere else.
+		var candidates []rune
+		for _, pool := range snapshot.Difficulty.RunePools {
+			for _, r := range pool {
+				if !visible[r] && !b.remembered[r] && !b.ruledOut[r] {
+					candidates = append(candidates, r)
+				}
+			}
+		}
+		pressed = candidates[b.random.Intn(len(candidates))]
+	}
+
+	if b.random.Float64() < b.settings.ErrorRate {
+		pressed = b.randomShownCharacter(snapshot, pressed)
+	}
+
+	hiddenBefore := snapshot.HiddenCellCount
+	b.session.PressRune(pressed)
+	if b.session.Snapshot().HiddenCellCount >= hiddenBefore {
+		if !visible[pressed] {
+			b.ruledOut[pressed] = true
+		}
+		b.presses = insertSorted(b.presses, b.elapsed()+b.settings.ReactionTime)
+	}
+}
+
+// randomShownCharacter returns a character that isn't hidden, as it's
+// still shown, mimicking a player confusing two characters. If there's
+// none, fallback is returned.
+func (b *bot) randomShownCharacter(snapshot Snapshot, fallback rune) rune {
+	var shown []rune
+	for _, cell := range snapshot.Board {
+		if cell.State == Shown {
+			shown = append(shown, cell.Character)
+		}
+	}
+	if len(shown) == 0 {
+		return fallback
+	}
+	return shown[b.random.Intn(len(shown))]
+}
+
+// memorize remembers up to Memory random characters of the current board.
+func (b *bot) memorize() {
+	snapshot := b.session.Snapshot()
+	b.round = snapshot.Round
+	b.remembered = make(map[rune]bool, b.settings.Memory)
+	b.ruledOut = make(map[rune]bool)
+	for _, index := range b.random.Perm(len(snapshot.Board)) {
+		if len(b.remembered) >= b.settings.Memory {
+			break
+		}
+		b.remembered[snapshot.Board[index].Character] = true
+	}
+}
+
+// advanceTo moves the clock to the given offset from the start.
+func (b *bot) advanceTo(offset time.Duration) {
+	if remaining := offset - b.elapsed(); remaining > 0 {
+		b.clock.Advance(remaining)
+	}
+}
+
+// elapsed returns the time passed since the start of the session.
+func (b *bot) elapsed() time.Duration {
+	return b.clock.Now().Sub(b.start)
+}
+
+// insertSorted adds the offset to the ascending list of offsets.
+func insertSorted(offsets []time.Duration, offset time.Duration) []time.Duration {
+	index := len(offsets)
+	for index > 0 && offsets[index-1] > offset {
+		index--
+	}
+	offsets = append(offsets, 0)
+	copy(offsets[index+1:], offsets[index:])
+	offsets[index] = offset
+	return offsets
+}
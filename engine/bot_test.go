@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	perfect := BotSettings{Memory: 100, ReactionTime: 100 * time.Millisecond}
+	for _, diff := range BuiltInDifficulties() {
+		snapshot, simulationError := Simulate(diff, ClassicMode, 1, perfect)
+		if simulationError != nil {
+			t.Fatal(simulationError)
+		}
+		if snapshot.State != Victory || snapshot.InvalidKeyPresses != 0 {
+			t.Errorf("perfect bot didn't win %s flawlessly: %s with %d invalid key presses",
+				diff.VisibleName, snapshot.State, snapshot.InvalidKeyPresses)
+		}
+	}
+
+	//A bot that doesn't remember anything and reacts slower than the cells
+	//are hidden can't keep up.
+	forgetful := BotSettings{Memory: 0, ReactionTime: 5 * time.Second, ErrorRate: 0.5}
+	nightmare := BuiltInDifficulties()[4]
+	snapshot, simulationError := Simulate(nightmare, ClassicMode, 1, forgetful)
+	if simulationError != nil {
+		t.Fatal(simulationError)
+	}
+	if snapshot.State != GameOver {
+		t.Errorf("forgetful bot didn't lose on %s", nightmare.VisibleName)
+	}
+
+	again, _ := Simulate(nightmare, ClassicMode, 1, forgetful)
+	if again.Score != snapshot.Score || again.Duration != snapshot.Duration {
+		t.Error("two simulations with the same arguments had different results")
+	}
+
+	//Endless games are surrendered eventually, even if the bot never loses.
+	endless, simulationError := Simulate(BuiltInDifficulties()[0], EndlessMode, 1, perfect)
+	if simulationError != nil {
+		t.Fatal(simulationError)
+	}
+	if endless.State != GameOver || endless.Round < 2 {
+		t.Errorf("unexpected end of endless game: %s in round %d", endless.State, endless.Round)
+	}
+}
+
+func TestSimulateRejectsInvalidInput(t *testing.T) {
+	diff := BuiltInDifficulties()[1]
+	if _, simulationError := Simulate(diff, PairsMode, 1, BotSettings{ReactionTime: time.Second}); simulationError == nil {
+		t.Error("pairs mode was simulated")
+	}
+	invalidSettings := []BotSettings{
+		{Memory: -1, ReactionTime: time.Second},
+		{Memory: 1},
+		{Memory: 1, ReactionTime: time.Second, ErrorRate: 1.5},
+	}
+	for _, settings := range invalidSettings {
+		if _, simulationError := Simulate(diff, ClassicMode, 1, settings); simulationError == nil {
+			t.Errorf("invalid settings %+v were accepted", settings)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package engine
+
+import "time"
+
+// Clock is the source of time for a Session. It allows controlling the
+// passing of time instead of having to wait for the wall clock, for
+// example in tests or simulations.
+type Clock interface {
+	Now() time.Time
+	NewTimer(duration time.Duration) Timer
+}
+
+// Timer fires once on its channel after its duration has passed.
+type Timer interface {
+	Channel() <-chan time.Time
+	//Stop prevents the timer from firing. It returns false if the timer has
+	//already fired or has been stopped before.
+	Stop() bool
+}
+
+// WallClock is the clock used during actual games.
+type WallClock struct{}
+
+// Now returns the current local time.
+func (WallClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer creates a timer backed by time.Timer.
+func (WallClock) NewTimer(duration time.Duration) Timer {
+	return wallTimer{time.NewTimer(duration)}
+}
+
+type wallTimer struct {
+	*time.Timer
+}
+
+func (t wallTimer) Channel() <-chan time.Time {
+	return t.C
+}
+
+func (t wallTimer) Stop() bool {
+	return t.Timer.Stop()
+}
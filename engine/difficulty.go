@@ -0,0 +1,420 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Difficulty defines the rules and timing of a game.
+type Difficulty struct {
+	VisibleName string
+
+	StartDelay time.Duration
+	HideTimes  time.Duration
+
+	CorrectGuessPoints      int
+	InvalidKeyPressPenality int
+
+	RowCount    int
+	ColumnCount int
+	RunePools   [][]rune
+
+	//MaxHiddenRatio is the ratio of hidden cells at which the player loses.
+	//Zero means that there's no limit relative to the board size.
+	MaxHiddenRatio float64
+	//MaxHiddenCount is the amount of hidden cells at which the player
+	//loses. Zero means that there's no absolute limit. If both limits are
+	//set, whichever is reached first ends the game.
+	MaxHiddenCount int
+	//MinimumWinningScore is the score required for winning once all cells
+	//have been guessed. Anything less is deemed a loss, as the player
+	//probably smashed their keyboard randomly.
+	MinimumWinningScore int
+
+	//SpeedBonus is the amount of additional points for guessing a cell
+	//right after it has been hidden. The bonus decays the longer it takes
+	//to guess the cell. Zero disables time-weighted scoring.
+	SpeedBonus int
+	//SpeedBonusHalfLife is the time after which the bonus for a guess has
+	//decayed to half of SpeedBonus.
+	SpeedBonusHalfLife time.Duration
+}
+
+const (
+	//DefaultMaxHiddenRatio is used by all built-in difficulties and by
+	//custom difficulties that don't define any hidden limit.
+	DefaultMaxHiddenRatio = 0.4
+	//DefaultMinimumWinningScore is used by custom difficulties that don't
+	//define a minimum winning score.
+	DefaultMinimumWinningScore = 1
+)
+
+// BuiltInDifficulties returns the difficulties that ship with the game,
+// ordered from easiest to hardest. Each call creates new instances, so the
+// caller is free to modify them.
+func BuiltInDifficulties() []*Difficulty {
+	return []*Difficulty{
+		{
+			VisibleName:        "easy",
+			CorrectGuessPoints: 5,
+			//You better take easy seriously!
+			InvalidKeyPressPenality: 4,
+			RowCount:                3,
+			ColumnCount:             2,
+			StartDelay:              750 * time.Millisecond,
+			HideTimes:               1250 * time.Millisecond,
+			MaxHiddenRatio:          DefaultMaxHiddenRatio,
+			MinimumWinningScore:     DefaultMinimumWinningScore,
+			RunePools: [][]rune{
+				RuneRange('1', '6'),
+			},
+		}, {
+			VisibleName:             "normal",
+			CorrectGuessPoints:      5,
+			InvalidKeyPressPenality: 2,
+			RowCount:                3,
+			ColumnCount:             3,
+			StartDelay:              1500 * time.Millisecond,
+			HideTimes:               1250 * time.Millisecond,
+			MaxHiddenRatio:          DefaultMaxHiddenRatio,
+			MinimumWinningScore:     DefaultMinimumWinningScore,
+			RunePools: [][]rune{
+				RuneRange('0', '9'),
+			},
+		}, {
+			VisibleName:             "hard",
+			CorrectGuessPoints:      5,
+			InvalidKeyPressPenality: 5,
+			RowCount:                3,
+			ColumnCount:             3,
+			StartDelay:              1500 * time.Millisecond,
+			HideTimes:               1500 * time.Millisecond,
+			MaxHiddenRatio:          DefaultMaxHiddenRatio,
+			MinimumWinningScore:     DefaultMinimumWinningScore,
+			RunePools: [][]rune{
+				RuneRange('a', 'z'),
+			},
+		}, {
+			VisibleName:             "extreme",
+			CorrectGuessPoints:      4,
+			InvalidKeyPressPenality: 5,
+			RowCount:                4,
+			ColumnCount:             3,
+			StartDelay:              1500 * time.Millisecond,
+			HideTimes:               1500 * time.Millisecond,
+			MaxHiddenRatio:          DefaultMaxHiddenRatio,
+			MinimumWinningScore:     DefaultMinimumWinningScore,
+			RunePools: [][]rune{
+				RuneRange('0', '9'),
+				RuneRange('a', 'z'),
+			},
+		}, {
+			VisibleName:             "nightmare",
+			CorrectGuessPoints:      4,
+			InvalidKeyPressPenality: 10,
+			RowCount:                5,
+			ColumnCount:             5,
+			StartDelay:              2500 * time.Millisecond,
+			HideTimes:               1500 * time.Millisecond,
+			MaxHiddenRatio:          DefaultMaxHiddenRatio,
+			MinimumWinningScore:     DefaultMinimumWinningScore,
+			RunePools: [][]rune{
+				RuneRange('0', '9'),
+				RuneRange('a', 'z'),
+			},
+		},
+	}
+}
+
+const (
+	//minimumEndlessHideTime is the fastest hide time an endless game can
+	//reach. Anything faster would be unplayable.
+	minimumEndlessHideTime = 300 * time.Millisecond
+	//endlessHideTimeFactor is applied to the hide time in each round of an
+	//endless game in which the board doesn't grow.
+	endlessHideTimeFactor = 0.9
+)
+
+// nextEndlessDifficulty derives the difficulty of the given round of an
+// endless game from the previous round's difficulty. Every other round the
+// board grows by one row or column, the rounds inbetween reduce the hide
+// time. If the rune pools are too small for a bigger board, the hide time
+// is reduced instead.
+func nextEndlessDifficulty(previous *Difficulty, round int) *Difficulty {
+	var poolSize int
+	for _, pool := range previous.RunePools {
+		poolSize += len(pool)
+	}
+
+	grown := *previous
+	if grown.RowCount <= grown.ColumnCount {
+		grown.RowCount++
+	} else {
+		grown.ColumnCount++
+	}
+	if round%2 == 0 && grown.RowCount*grown.ColumnCount <= poolSize {
+		return &grown
+	}
+
+	next := *previous
+	next.HideTimes = time.Duration(float64(previous.HideTimes) * endlessHideTimeFactor)
+	if next.HideTimes < minimumEndlessHideTime {
+		next.HideTimes = minimumEndlessHideTime
+	}
+
+	return &next
+}
+
+// Validate checks whether a game can be played using this difficulty. The
+// returned error describes the first problem found.
+func (d *Difficulty) Validate() error {
+	if d.VisibleName == "" {
+		return errors.New("the name must not be empty")
+	}
+
+	if d.StartDelay < 0 {
+		return fmt.Errorf("the start delay must not be negative; got %s", d.StartDelay)
+	}
+
+	if d.HideTimes <= 0 {
+		return fmt.Errorf("the hide time must be greater than 0; got %s", d.HideTimes)
+	}
+
+	if d.CorrectGuessPoints <= 0 {
+		return fmt.Errorf("the points for a correct guess must be greater than 0; got %d", d.CorrectGuessPoints)
+	}
+
+	if d.InvalidKeyPressPenality < 0 {
+		return fmt.Errorf("the penalty for invalid key presses must not be negative; got %d", d.InvalidKeyPressPenality)
+	}
+
+	if d.RowCount <= 0 || d.ColumnCount <= 0 {
+		return fmt.Errorf("the row and column count must be greater than 0; got %dx%d", d.RowCount, d.ColumnCount)
+	}
+
+	if d.MaxHiddenRatio < 0 || d.MaxHiddenRatio > 1 {
+		return fmt.Errorf("the maximum hidden ratio must be between 0 and 1; got %g", d.MaxHiddenRatio)
+	}
+
+	if cellCount := d.RowCount * d.ColumnCount; d.MaxHiddenCount < 0 || d.MaxHiddenCount > cellCount {
+		return fmt.Errorf("the maximum hidden count must be between 0 and the %d cells of the board; got %d",
+			cellCount, d.MaxHiddenCount)
+	}
+
+	if d.SpeedBonus < 0 {
+		return fmt.Errorf("the speed bonus must not be negative; got %d", d.SpeedBonus)
+	}
+
+	if d.SpeedBonus > 0 && d.SpeedBonusHalfLife <= 0 {
+		return fmt.Errorf("the half-life of the speed bonus must be greater than 0; got %s", d.SpeedBonusHalfLife)
+	}
+
+	//Each cell needs a unique character, as the player couldn't tell
+	//which cell they meant otherwise.
+	knownRunes := make(map[rune]bool)
+	for _, pool := range d.RunePools {
+		for _, r := range pool {
+			if knownRunes[r] {
+				return fmt.Errorf("the character '%c' occurs more than once in the rune pools", r)
+			}
+			knownRunes[r] = true
+		}
+	}
+
+	if cellCount := d.RowCount * d.ColumnCount; len(knownRunes) < cellCount {
+		return fmt.Errorf("the rune pools contain %d characters, but a %dx%d board needs at least %d",
+			len(knownRunes), d.RowCount, d.ColumnCount, cellCount)
+	}
+
+	return nil
+}
+
+// IsInputRune decides whether the given rune might have to be typed when
+// playing the difficulty in the given mode. Front ends can use this in
+// order to make sure that their own key bindings don't get in the way.
+func (d *Difficulty) IsInputRune(mode Mode, r rune) bool {
+	//In pairs mode, cells are addressed via their coordinates instead of
+	//their characters.
+	if mode == PairsMode {
+		pairsDiff := pairsDifficulty(d)
+		return (r >= ColumnLabel(0) && r < ColumnLabel(pairsDiff.RowCount)) ||
+			(r >= RowLabel(0) && r < RowLabel(pairsDiff.ColumnCount))
+	}
+
+	for _, pool := range d.RunePools {
+		for _, poolRune := range pool {
+			if poolRune == r {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// DifficultyDefinition is the JSON representation of a Difficulty. It is
+// used for custom difficulties and for embedding difficulties in recordings.
+type DifficultyDefinition struct {
+	VisibleName string `json:"name"`
+
+	//StartDelay and HideTimes are parsed via time.ParseDuration, e.g. "1500ms".
+	StartDelay string `json:"startDelay"`
+	HideTimes  string `json:"hideTimes"`
+
+	CorrectGuessPoints      int `json:"points"`
+	InvalidKeyPressPenality int `json:"penalty"`
+
+	RowCount    int `json:"rowCount"`
+	ColumnCount int `json:"columnCount"`
+	//RunePools contains either plain sets of characters, such as "abc", or
+	//inclusive ranges in the form of "a-z".
+	RunePools []string `json:"runePools"`
+
+	//MaxHiddenRatio and MinimumWinningScore are pointers, so that omitting
+	//them results in the default rules instead of zero. If only
+	//MaxHiddenCount is given, the default ratio doesn't apply.
+	MaxHiddenRatio      *float64 `json:"maxHiddenRatio,omitempty"`
+	MaxHiddenCount      int      `json:"maxHiddenCount,omitempty"`
+	MinimumWinningScore *int     `json:"minimumWinningScore,omitempty"`
+
+	//SpeedBonusHalfLife is parsed via time.ParseDuration and only required
+	//if SpeedBonus is set.
+	SpeedBonus         int    `json:"speedBonus,omitempty"`
+	SpeedBonusHalfLife string `json:"speedBonusHalfLife,omitempty"`
+}
+
+// NewDifficultyDefinition converts the difficulty into its JSON
+// representation. Rune pools are written as plain sets of characters.
+func NewDifficultyDefinition(diff *Difficulty) *DifficultyDefinition {
+	runePools := make([]string, 0, len(diff.RunePools))
+	for _, pool := range diff.RunePools {
+		runePools = append(runePools, string(pool))
+	}
+	maxHiddenRatio := diff.MaxHiddenRatio
+	minimumWinningScore := diff.MinimumWinningScore
+	var speedBonusHalfLife string
+	if diff.SpeedBonus > 0 {
+		speedBonusHalfLife = diff.SpeedBonusHalfLife.String()
+	}
+
+	return &DifficultyDefinition{
+		VisibleName:             diff.VisibleName,
+		StartDelay:              diff.StartDelay.String(),
+		HideTimes:               diff.HideTimes.String(),
+		CorrectGuessPoints:      diff.CorrectGuessPoints,
+		InvalidKeyPressPenality: diff.InvalidKeyPressPenality,
+		RowCount:                diff.RowCount,
+		ColumnCount:             diff.ColumnCount,
+		RunePools:               runePools,
+		MaxHiddenRatio:          &maxHiddenRatio,
+		MaxHiddenCount:          diff.MaxHiddenCount,
+		MinimumWinningScore:     &minimumWinningScore,
+		SpeedBonus:              diff.SpeedBonus,
+		SpeedBonusHalfLife:      speedBonusHalfLife,
+	}
+}
+
+// ToDifficulty converts the definition into a playable difficulty. If the
+// definition isn't valid, an error is returned.
+func (definition *DifficultyDefinition) ToDifficulty() (*Difficulty, error) {
+	startDelay, startDelayError := time.ParseDuration(definition.StartDelay)
+	if startDelayError != nil {
+		return nil, fmt.Errorf("invalid start delay: %w", startDelayError)
+	}
+
+	hideTimes, hideTimesError := time.ParseDuration(definition.HideTimes)
+	if hideTimesError != nil {
+		return nil, fmt.Errorf("invalid hide time: %w", hideTimesError)
+	}
+
+	runePools := make([][]rune, 0, len(definition.RunePools))
+	for _, pool := range definition.RunePools {
+		runePools = append(runePools, ParseRunePool(pool))
+	}
+
+	diff := &Difficulty{
+		VisibleName:             definition.VisibleName,
+		StartDelay:              startDelay,
+		HideTimes:               hideTimes,
+		CorrectGuessPoints:      definition.CorrectGuessPoints,
+		InvalidKeyPressPenality: definition.InvalidKeyPressPenality,
+		RowCount:                definition.RowCount,
+		ColumnCount:             definition.ColumnCount,
+		RunePools:               runePools,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MaxHiddenCount:          definition.MaxHiddenCount,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		SpeedBonus:              definition.SpeedBonus,
+	}
+	if definition.MaxHiddenRatio != nil {
+		diff.MaxHiddenRatio = *definition.MaxHiddenRatio
+	} else if definition.MaxHiddenCount != 0 {
+		//An absolute limit on its own replaces the default ratio.
+		diff.MaxHiddenRatio = 0
+	}
+	if definition.MinimumWinningScore != nil {
+		diff.MinimumWinningScore = *definition.MinimumWinningScore
+	}
+	if definition.SpeedBonusHalfLife != "" {
+		halfLife, halfLifeError := time.ParseDuration(definition.SpeedBonusHalfLife)
+		if halfLifeError != nil {
+			return nil, fmt.Errorf("invalid speed bonus half-life: %w", halfLifeError)
+		}
+		diff.SpeedBonusHalfLife = halfLife
+	}
+
+	if validationError := diff.Validate(); validationError != nil {
+		return nil, validationError
+	}
+
+	return diff, nil
+}
+
+// ParseRunePool turns a pool definition into the runes it describes.
+// Three characters with a dash in the middle, such as "a-z", are treated as
+// an inclusive range. Anything else is taken literally.
+func ParseRunePool(pool string) []rune {
+	runes := []rune(pool)
+	if len(runes) == 3 && runes[1] == '-' && runes[0] < runes[2] {
+		return RuneRange(runes[0], runes[2])
+	}
+
+	return runes
+}
+
+// RuneRange creates a new rune array containing all the runes between the
+// two passed ones. Both from and to are inclusive.
+func RuneRange(from, to rune) []rune {
+	runes := make([]rune, 0, to-from+1)
+	for r := from; r <= to; r++ {
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// CharacterSet creates a unique set of characters to be used for the
+// game board. The size must be greater than 0. For sourcing the
+// characters, the rune arrays passed to this method will be used. The
+// characters are picked using the given source of randomness.
+func CharacterSet(random *rand.Rand, size int, pools ...[]rune) ([]rune, error) {
+	var availableCharacters []rune
+	for _, pool := range pools {
+		availableCharacters = append(availableCharacters, pool...)
+	}
+
+	if size > len(availableCharacters) {
+		return nil, fmt.Errorf("the characterset can't be bigger than %d; you passed %d", len(availableCharacters), size)
+	}
+
+	if size <= 0 {
+		return nil, errors.New("the request amount of characters must be greater than 0")
+	}
+
+	random.Shuffle(len(availableCharacters), func(a, b int) {
+		availableCharacters[a], availableCharacters[b] = availableCharacters[b], availableCharacters[a]
+	})
+
+	return availableCharacters[0:size], nil
+}
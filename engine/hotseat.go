@@ -0,0 +1,64 @@
+package engine
+
+// hotSeatPlayerCount is the amount of players sharing the keyboard in
+// hot-seat mode.
+const hotSeatPlayerCount = 2
+
+// PlayerScore is the part of a hot-seat session's result achieved by a
+// single player.
+type PlayerScore struct {
+	Score int
+	//ScoreBreakdown contains the parts the player's score is made up of.
+	ScoreBreakdown    ScoreBreakdown
+	GuessedCount      int
+	InvalidKeyPresses int
+
+	//speedBonus is the sum of the player's bonuses for quick guesses.
+	speedBonus int
+}
+
+// startHotSeat prepares the accounting for all players. The first player
+// starts.
+func (s *Session) startHotSeat() {
+	s.players = make([]PlayerScore, hotSeatPlayerCount)
+	s.currentPlayer = 0
+}
+
+// passTurn hands the keyboard to the next player in hot-seat mode. Each
+// key press is a turn of its own, no matter whether it was correct.
+func (s *Session) passTurn() {
+	if s.mode != HotSeatMode {
+		return
+	}
+
+	s.currentPlayer = (s.currentPlayer + 1) % len(s.players)
+}
+
+// updatePlayerScores calculates the score of each player in hot-seat mode.
+func (s *Session) updatePlayerScores() {
+	for index := range s.players {
+		player := &s.players[index]
+		player.ScoreBreakdown = ScoreBreakdown{
+			Base:       player.GuessedCount * s.difficulty.CorrectGuessPoints,
+			SpeedBonus: player.speedBonus,
+			Penalty:    player.InvalidKeyPresses * s.difficulty.InvalidKeyPressPenality,
+		}
+		player.Score = player.ScoreBreakdown.Total()
+	}
+}
+
+// Leader returns the index of the player with the highest score in
+// hot-seat mode. If several players share the highest score or the
+// session isn't a hot-seat session, false is returned.
+func (snapshot Snapshot) Leader() (int, bool) {
+	leader, tied := -1, false
+	for index, player := range snapshot.Players {
+		if leader == -1 || player.Score > snapshot.Players[leader].Score {
+			leader, tied = index, false
+		} else if player.Score == snapshot.Players[leader].Score {
+			tied = true
+		}
+	}
+
+	return leader, leader != -1 && !tied
+}
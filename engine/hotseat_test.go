@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHotSeatMode(t *testing.T) {
+	testClock := NewManualClock(time.Time{})
+	//easy gives 5 points per cell and takes 4 per invalid key press.
+	session := newTestSession(t, BuiltInDifficulties()[0], HotSeatMode, 1, testClock)
+
+	hiddenCharacter := func() rune {
+		for _, cell := range session.Snapshot().Board {
+			if cell.State == Hidden {
+				return cell.Character
+			}
+		}
+		t.Fatal("no cell is hidden")
+		return 0
+	}
+
+	//Player 1 guesses, player 2 presses a wrong key, player 1 guesses
+	//again. Afterwards it's player 2's turn.
+	session.Tick()
+	session.PressRune(hiddenCharacter())
+	session.PressRune('x')
+	session.Tick()
+	session.PressRune(hiddenCharacter())
+
+	snapshot := session.Snapshot()
+	if snapshot.CurrentPlayer != 1 {
+		t.Errorf("expected player 2's turn, got player %d", snapshot.CurrentPlayer+1)
+	}
+	if len(snapshot.Players) != 2 {
+		t.Fatalf("expected two players, got %d", len(snapshot.Players))
+	}
+	first, second := snapshot.Players[0], snapshot.Players[1]
+	if first.Score != 10 || first.GuessedCount != 2 || first.InvalidKeyPresses != 0 {
+		t.Errorf("unexpected result of player 1: %+v", first)
+	}
+	if second.Score != -4 || second.GuessedCount != 0 || second.InvalidKeyPresses != 1 {
+		t.Errorf("unexpected result of player 2: %+v", second)
+	}
+	if snapshot.Score != first.Score+second.Score {
+		t.Errorf("session score %d isn't the sum of the player scores", snapshot.Score)
+	}
+	if leader, hasLeader := snapshot.Leader(); !hasLeader || leader != 0 {
+		t.Errorf("expected player 1 to lead, got %d (%t)", leader, hasLeader)
+	}
+
+	tied := Snapshot{Players: []PlayerScore{{Score: 3}, {Score: 3}}}
+	if _, hasLeader := tied.Leader(); hasLeader {
+		t.Error("a leader was found despite a tie")
+	}
+	if _, hasLeader := (Snapshot{}).Leader(); hasLeader {
+		t.Error("a leader was found in a session that isn't hot-seat")
+	}
+}
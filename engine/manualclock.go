@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a Clock that only moves forward when told to. Timers fire
+// as soon as their deadline has been reached via Advance. It's used for
+// replays, tests and simulations.
+type ManualClock struct {
+	mutex   *sync.Mutex
+	current time.Time
+	pending []*manualTimer
+}
+
+type manualTimer struct {
+	clock    *ManualClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewManualClock creates a clock that starts at the given point in time.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{
+		mutex:   &sync.Mutex{},
+		current: start,
+	}
+}
+
+// Now returns the clock's current point in time.
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.current
+}
+
+// NewTimer creates a timer that fires once the clock has been advanced by
+// at least the given duration.
+func (c *ManualClock) NewTimer(duration time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	newTimer := &manualTimer{
+		clock:    c,
+		deadline: c.current.Add(duration),
+		c:        make(chan time.Time, 1),
+	}
+	if duration <= 0 {
+		newTimer.c <- c.current
+	} else {
+		c.pending = append(c.pending, newTimer)
+	}
+	return newTimer
+}
+
+// Advance moves the clock forward and fires all timers whose deadline has
+// been reached.
+func (c *ManualClock) Advance(duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.current = c.current.Add(duration)
+	stillPending := c.pending[:0]
+	for _, pendingTimer := range c.pending {
+		if pendingTimer.deadline.After(c.current) {
+			stillPending = append(stillPending, pendingTimer)
+		} else {
+			pendingTimer.c <- c.current
+		}
+	}
+	c.pending = stillPending
+}
+
+// PendingTimers returns the amount of timers that haven't fired yet. This
+// allows waiting for goroutines to go to sleep before advancing the clock.
+func (c *ManualClock) PendingTimers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.pending)
+}
+
+func (t *manualTimer) Channel() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	for index, pendingTimer := range t.clock.pending {
+		if pendingTimer == t {
+			t.clock.pending = append(t.clock.pending[:index], t.clock.pending[index+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
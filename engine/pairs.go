@@ -0,0 +1,175 @@
+package engine
+
+import "fmt"
+
+const (
+	//maxPairsColumns is the amount of columns that can be addressed via
+	//the letters a to z.
+	maxPairsColumns = 26
+	//maxPairsRows is the amount of rows that can be addressed via the
+	//digits 1 to 9.
+	maxPairsRows = 9
+)
+
+// ValidateMode checks whether the difficulty can be played in the given
+// mode. This is required in addition to Validate, as some modes have
+// additional constraints.
+func (d *Difficulty) ValidateMode(mode Mode) error {
+	if mode != PairsMode {
+		return nil
+	}
+
+	pairsDiff := pairsDifficulty(d)
+	if pairsDiff.RowCount > maxPairsColumns || pairsDiff.ColumnCount > maxPairsRows {
+		return fmt.Errorf("%s can't be played in pairs mode, as a %dx%d board can't be addressed via coordinates",
+			d.VisibleName, pairsDiff.RowCount, pairsDiff.ColumnCount)
+	}
+
+	return nil
+}
+
+// pairsDifficulty returns a difficulty that can be used for playing pairs.
+// Since each rune occurs twice, the board needs an even amount of cells.
+// Boards with an odd amount of cells get an additional column.
+func pairsDifficulty(diff *Difficulty) *Difficulty {
+	if diff.RowCount*diff.ColumnCount%2 == 0 {
+		return diff
+	}
+
+	pairsDiff := *diff
+	pairsDiff.RowCount++
+	return &pairsDiff
+}
+
+// ColumnLabel returns the character used for addressing the column with
+// the given index in pairs mode.
+func ColumnLabel(x int) rune {
+	return rune('a' + x)
+}
+
+// RowLabel returns the character used for addressing the row with the
+// given index in pairs mode.
+func RowLabel(y int) rune {
+	return rune('1' + y)
+}
+
+// fillPairsBoard creates a board that contains each rune twice. All cells
+// are hidden at once after the preview, so the hide order doesn't matter.
+func (s *Session) fillPairsBoard() error {
+	cellCount := s.difficulty.RowCount * s.difficulty.ColumnCount
+	characterSet, charSetError := CharacterSet(s.random, cellCount/2, s.difficulty.RunePools...)
+	if charSetError != nil {
+		return charSetError
+	}
+
+	s.gameBoard = make([]*Cell, 0, cellCount)
+	for _, char := range characterSet {
+		s.gameBoard = append(s.gameBoard, &Cell{Character: char, State: Shown}, &Cell{Character: char, State: Shown})
+	}
+	s.random.Shuffle(len(s.gameBoard), func(a, b int) {
+		s.gameBoard[a], s.gameBoard[b] = s.gameBoard[b], s.gameBoard[a]
+	})
+
+	s.indicesToHide = make([]int, len(s.gameBoard))
+	for i := 0; i < len(s.indicesToHide); i++ {
+		s.indicesToHide[i] = i
+	}
+	return nil
+}
+
+// endPreview hides all cells of a pairs game at once. From then on, the
+// player can reveal cells.
+func (s *Session) endPreview() {
+	s.record(&RecordedEvent{Kind: PreviewEndEvent})
+	for _, cell := range s.gameBoard {
+		cell.State = Hidden
+	}
+	s.indicesToHide = nil
+	s.updateGameState()
+}
+
+// inputPairsRune handles key presses in pairs mode. A cell is revealed by
+// typing its column letter followed by its row number, e.g. "b3".
+func (s *Session) inputPairsRune(pressed rune) {
+	//Cells can't be revealed before they have been hidden.
+	if len(s.indicesToHide) > 0 {
+		return
+	}
+
+	if x := int(pressed - 'a'); x >= 0 && x < s.difficulty.RowCount {
+		s.pendingColumn = pressed
+		return
+	}
+
+	if y := int(pressed - '1'); s.pendingColumn != 0 && y >= 0 && y < s.difficulty.ColumnCount {
+		x := int(s.pendingColumn - 'a')
+		s.pendingColumn = 0
+		s.revealCell(x + s.difficulty.RowCount*y)
+		return
+	}
+
+	s.pressWrongKey(pressed)
+	s.updateGameState()
+}
+
+// SelectCell targets the cell with the given index, as if its coordinate
+// had been typed. Only pairs mode involves picking positions, in all other
+// modes this has no effect.
+func (s *Session) SelectCell(index int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.selectCell(index)
+	s.notify()
+}
+
+func (s *Session) selectCell(index int) {
+	if s.state != Ongoing || s.isPaused() || s.mode != PairsMode ||
+		index < 0 || index >= len(s.gameBoard) {
+		return
+	}
+
+	//Cells can't be revealed before they have been hidden.
+	if len(s.indicesToHide) > 0 {
+		return
+	}
+
+	s.record(&RecordedEvent{Kind: SelectEvent, Index: index})
+	s.pendingColumn = 0
+	s.revealCell(index)
+}
+
+// revealCell shows the character of a hidden cell. If it's the second cell
+// revealed, both cells are compared. Matching pairs stay visible, while
+// pairs that don't match are hidden again once the next cell is revealed.
+// A pair that doesn't match counts as an invalid key press.
+func (s *Session) revealCell(index int) {
+	if len(s.revealed) == 2 {
+		for _, revealedIndex := range s.revealed {
+			s.gameBoard[revealedIndex].State = Hidden
+		}
+		s.revealed = nil
+	}
+
+	cell := s.gameBoard[index]
+	if cell.State != Hidden {
+		s.invalidKeyPresses++
+		s.updateGameState()
+		return
+	}
+
+	cell.State = Revealed
+	s.revealed = append(s.revealed, index)
+	if len(s.revealed) == 2 {
+		first, second := s.gameBoard[s.revealed[0]], s.gameBoard[s.revealed[1]]
+		if first.Character == second.Character {
+			first.State = Guessed
+			second.State = Guessed
+			s.revealed = nil
+		} else {
+			s.invalidKeyPresses++
+		}
+	}
+
+	s.updateGameState()
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPairsMode(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "pairs",
+		HideTimes:               time.Second,
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		RowCount:                3,
+		ColumnCount:             3,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '9'),
+		},
+	}
+
+	session := newTestSession(t, testDifficulty, PairsMode, 1, NewManualClock(time.Time{}))
+	snapshot := session.Snapshot()
+	//A 3x3 board can't be filled with pairs, so it grows by one column.
+	if snapshot.Difficulty.RowCount != 4 || len(snapshot.Board) != 12 {
+		t.Fatalf("expected a 4x3 board, got %dx%d with %d cells",
+			snapshot.Difficulty.RowCount, snapshot.Difficulty.ColumnCount, len(snapshot.Board))
+	}
+	positions := make(map[rune][]int)
+	for index, cell := range snapshot.Board {
+		if cell.State != Shown {
+			t.Errorf("cell %d isn't shown during the preview", index)
+		}
+		positions[cell.Character] = append(positions[cell.Character], index)
+	}
+	for char, indices := range positions {
+		if len(indices) != 2 {
+			t.Errorf("rune %c occurs %d times", char, len(indices))
+		}
+	}
+
+	reveal := func(index int) {
+		session.PressRune(ColumnLabel(index % 4))
+		session.PressRune(RowLabel(index / 4))
+	}
+
+	//Input during the preview is ignored.
+	reveal(0)
+	session.Tick()
+	if snapshot := session.Snapshot(); snapshot.HiddenCellCount != 12 || snapshot.State != Ongoing {
+		t.Fatalf("expected all 12 cells to be hidden, got %d", snapshot.HiddenCellCount)
+	}
+
+	//Two cells that don't match stay visible until the next cell is
+	//revealed.
+	first := snapshot.Board[0].Character
+	mismatch := positions[first][1]
+	for index, cell := range snapshot.Board {
+		if cell.Character != first {
+			mismatch = index
+			break
+		}
+	}
+	reveal(0)
+	reveal(mismatch)
+	snapshot = session.Snapshot()
+	if snapshot.Board[0].State != Revealed || snapshot.Board[mismatch].State != Revealed ||
+		snapshot.InvalidKeyPresses != 1 {
+		t.Fatalf("expected two revealed cells and one invalid key press, got %+v, %+v and %d",
+			snapshot.Board[0], snapshot.Board[mismatch], snapshot.InvalidKeyPresses)
+	}
+
+	for _, indices := range positions {
+		reveal(indices[0])
+		reveal(indices[1])
+	}
+	snapshot = session.Snapshot()
+	if snapshot.State != Victory || snapshot.Score != 6*5-2 {
+		t.Errorf("expected victory with 28 points, got %s with %d points", snapshot.State, snapshot.Score)
+	}
+
+	//Coordinates outside of the board are invalid.
+	other := newTestSession(t, testDifficulty, PairsMode, 1, NewManualClock(time.Time{}))
+	other.Tick()
+	other.PressRune('z')
+	other.PressRune('1')
+	if invalid := other.Snapshot().InvalidKeyPresses; invalid != 2 {
+		t.Errorf("%d invalid key presses, expected 2", invalid)
+	}
+}
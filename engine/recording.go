@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventKind describes what has happened in a RecordedEvent.
+type EventKind string
+
+const (
+	HideEvent        EventKind = "hide"
+	RunePressEvent   EventKind = "press"
+	SurrenderEvent   EventKind = "surrender"
+	StateChangeEvent EventKind = "state"
+	RoundEvent       EventKind = "round"
+	PauseEvent       EventKind = "pause"
+	ResumeEvent      EventKind = "resume"
+	PreviewEndEvent  EventKind = "preview"
+	SelectEvent      EventKind = "select"
+)
+
+// RecordedEvent is a single thing that happened during a session. Only the
+// fields relevant for the kind of event are set.
+type RecordedEvent struct {
+	//Offset is the time passed between the start of the session and the
+	//event.
+	Offset time.Duration `json:"offset"`
+	Kind   EventKind     `json:"kind"`
+
+	//Index is the index of the cell that has been hidden or selected.
+	Index int `json:"index,omitempty"`
+	//Rune is the character the player has pressed.
+	Rune string `json:"rune,omitempty"`
+	//State is the state the session has changed to.
+	State string `json:"state,omitempty"`
+	//Round is the number of the round that has just started.
+	Round int `json:"round,omitempty"`
+}
+
+// Recording contains everything required to replay a session. Since the
+// board is generated from the seed, only the events have to be stored. The
+// difficulty is embedded, so that sessions using custom difficulties can be
+// replayed by other players as well.
+type Recording struct {
+	Seed       int64                 `json:"seed"`
+	Mode       string                `json:"mode"`
+	Difficulty *DifficultyDefinition `json:"difficulty"`
+	StartTime  time.Time             `json:"startTime"`
+	Events     []*RecordedEvent      `json:"events"`
+}
+
+// NewReplaySession creates a session that the recording's events can be
+// applied to via Session.Apply. The returned clock has to be advanced to
+// each event's offset before applying it.
+func (rec *Recording) NewReplaySession() (*Session, *ManualClock, error) {
+	if rec.Difficulty == nil {
+		return nil, nil, fmt.Errorf("the recording doesn't contain a difficulty")
+	}
+
+	diff, difficultyError := rec.Difficulty.ToDifficulty()
+	if difficultyError != nil {
+		return nil, nil, difficultyError
+	}
+
+	mode, modeError := ParseMode(rec.Mode)
+	if modeError != nil {
+		return nil, nil, modeError
+	}
+
+	clock := NewManualClock(rec.StartTime)
+	session, sessionError := NewSession(diff, mode, rec.Seed, clock)
+	if sessionError != nil {
+		return nil, nil, sessionError
+	}
+	return session, clock, nil
+}
+
+// Apply replays a single recorded event on the session. State changes
+// aren't applied, since they are a result of the other events. Instead
+// they are used to make sure that the replay is still in sync.
+func (s *Session) Apply(event *RecordedEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.notify()
+
+	switch event.Kind {
+	case HideEvent:
+		if len(s.indicesToHide) == 0 ||
+			s.indicesToHide[len(s.indicesToHide)-1] != event.Index {
+			return fmt.Errorf("replay out of sync at %s: cell %d can't be hidden next", event.Offset, event.Index)
+		}
+		s.hideRune()
+	case PreviewEndEvent:
+		if s.mode != PairsMode || len(s.indicesToHide) == 0 {
+			return fmt.Errorf("replay out of sync at %s: there's no preview to end", event.Offset)
+		}
+		s.endPreview()
+	case SelectEvent:
+		s.selectCell(event.Index)
+	case RunePressEvent:
+		for _, pressed := range event.Rune {
+			s.inputRunePress(pressed)
+		}
+	case SurrenderEvent:
+		s.surrender()
+	case PauseEvent:
+		//The original reason doesn't matter, as the session only needs to
+		//know that it has been paused.
+		s.pause(PausedByPlayer)
+	case ResumeEvent:
+		s.resume(s.pauseReasons)
+	case StateChangeEvent:
+		if s.state.String() != event.State {
+			return fmt.Errorf("replay out of sync at %s: state is %s instead of %s", event.Offset, s.state, event.State)
+		}
+	case RoundEvent:
+		if s.round != event.Round {
+			return fmt.Errorf("replay out of sync at %s: round is %d instead of %d", event.Offset, s.round, event.Round)
+		}
+	default:
+		return fmt.Errorf("unknown event kind '%s'", event.Kind)
+	}
+
+	return nil
+}
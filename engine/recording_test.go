@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestRecordingRoundTrip plays a session, serializes its recording and
+// makes sure that replaying it results in the same final session.
+func TestRecordingRoundTrip(t *testing.T) {
+	testClock := NewManualClock(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+	original := newTestSession(t, BuiltInDifficulties()[1], ClassicMode, 7, testClock)
+	for i := 0; i < 3; i++ {
+		testClock.Advance(time.Second)
+		original.Tick()
+	}
+	testClock.Advance(200 * time.Millisecond)
+	for _, cell := range original.Snapshot().Board {
+		if cell.State == Hidden {
+			original.PressRune(cell.Character)
+			break
+		}
+	}
+	original.PressRune('-')
+	testClock.Advance(time.Second)
+	original.Surrender()
+
+	data, marshalError := json.Marshal(original.Recording())
+	if marshalError != nil {
+		t.Fatal(marshalError)
+	}
+	var rec Recording
+	if parseError := json.Unmarshal(data, &rec); parseError != nil {
+		t.Fatal(parseError)
+	}
+
+	replayed, clock, replayError := rec.NewReplaySession()
+	if replayError != nil {
+		t.Fatal(replayError)
+	}
+	for _, event := range rec.Events {
+		clock.Advance(rec.StartTime.Add(event.Offset).Sub(clock.Now()))
+		if applyError := replayed.Apply(event); applyError != nil {
+			t.Fatal(applyError)
+		}
+	}
+
+	want, got := original.Snapshot(), replayed.Snapshot()
+	if got.State != GameOver || got.Score != want.Score ||
+		got.InvalidKeyPresses != want.InvalidKeyPresses {
+		t.Errorf("replayed session ended with %s, %d points and %d invalid key presses; expected %s, %d, %d",
+			got.State, got.Score, got.InvalidKeyPresses,
+			want.State, want.Score, want.InvalidKeyPresses)
+	}
+	if got.Duration != want.Duration {
+		t.Errorf("replayed session took %s, expected %s", got.Duration, want.Duration)
+	}
+	for index, cell := range got.Board {
+		if cell != want.Board[index] {
+			t.Errorf("cell %d differs: %+v vs %+v", index, cell, want.Board[index])
+		}
+	}
+}
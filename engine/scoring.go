@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+// ScoreBreakdown splits a score into the parts it has been made up of.
+type ScoreBreakdown struct {
+	//Base is the amount of points given for all correct guesses.
+	Base int
+	//SpeedBonus is the amount of points given for guessing cells quickly.
+	//It's only used by difficulties with time-weighted scoring.
+	SpeedBonus int
+	//Penalty is the amount of points lost due to invalid key presses.
+	Penalty int
+}
+
+// Total returns the score resulting from the breakdown.
+func (breakdown ScoreBreakdown) Total() int {
+	return breakdown.Base + breakdown.SpeedBonus - breakdown.Penalty
+}
+
+// SpeedBonusFor returns the bonus for guessing a cell the given time after
+// it has been hidden. The bonus decays exponentially, halving every
+// SpeedBonusHalfLife.
+func (d *Difficulty) SpeedBonusFor(reactionTime time.Duration) int {
+	if d.SpeedBonus == 0 {
+		return 0
+	}
+
+	halfLives := float64(reactionTime) / float64(d.SpeedBonusHalfLife)
+	return int(math.Round(float64(d.SpeedBonus) * math.Pow(0.5, halfLives)))
+}
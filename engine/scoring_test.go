@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWeightedScoring(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "speedy",
+		HideTimes:               time.Second,
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 3,
+		RowCount:                3,
+		ColumnCount:             2,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		SpeedBonus:              8,
+		SpeedBonusHalfLife:      time.Second,
+		RunePools: [][]rune{
+			RuneRange('1', '6'),
+		},
+	}
+
+	for reactionTime, expected := range map[time.Duration]int{
+		0:                       8,
+		time.Second:             4,
+		2 * time.Second:         2,
+		1500 * time.Millisecond: 3,
+		time.Minute:             0,
+	} {
+		if bonus := testDifficulty.SpeedBonusFor(reactionTime); bonus != expected {
+			t.Errorf("expected a bonus of %d after %s, got %d", expected, reactionTime, bonus)
+		}
+	}
+
+	clock := NewManualClock(time.Time{})
+	session := newTestSession(t, testDifficulty, ClassicMode, 1, clock)
+	guessHidden := func() {
+		for _, cell := range session.Snapshot().Board {
+			if cell.State == Hidden {
+				session.PressRune(cell.Character)
+				return
+			}
+		}
+		t.Fatal("no cell is hidden")
+	}
+
+	session.Tick()
+	guessHidden()
+	session.Tick()
+	clock.Advance(time.Second)
+	guessHidden()
+	session.PressRune('x')
+
+	snapshot := session.Snapshot()
+	expected := ScoreBreakdown{Base: 10, SpeedBonus: 12, Penalty: 3}
+	if snapshot.ScoreBreakdown != expected || snapshot.Score != 19 {
+		t.Errorf("expected %+v with a score of 19, got %+v with %d", expected, snapshot.ScoreBreakdown, snapshot.Score)
+	}
+
+	//Without a speed bonus, all guesses are worth the same.
+	flat := *testDifficulty
+	flat.SpeedBonus = 0
+	if bonus := flat.SpeedBonusFor(0); bonus != 0 {
+		t.Errorf("expected no bonus, got %d", bonus)
+	}
+}
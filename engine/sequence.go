@@ -0,0 +1,72 @@
+package engine
+
+// startSequence remembers the order in which the cells are hidden and
+// prepares the first round of a sequence game. Every round hides the same
+// cells in the same order, but one more than the previous round.
+func (s *Session) startSequence() {
+	s.sequence = make([]int, 0, len(s.indicesToHide))
+	for i := len(s.indicesToHide) - 1; i >= 0; i-- {
+		s.sequence = append(s.sequence, s.indicesToHide[i])
+	}
+	s.prepareSequenceRound()
+}
+
+// prepareSequenceRound shows all cells again and schedules as many cells
+// to be hidden as the current round's number.
+func (s *Session) prepareSequenceRound() {
+	for _, cell := range s.gameBoard {
+		cell.State = Shown
+	}
+
+	//indicesToHide is a stack, therefore the first cell of the sequence
+	//has to be at the end.
+	s.indicesToHide = make([]int, 0, s.round)
+	for i := s.round - 1; i >= 0; i-- {
+		s.indicesToHide = append(s.indicesToHide, s.sequence[i])
+	}
+	s.sequenceProgress = 0
+	s.sequenceRevealed = 0
+	s.hiddenCount = 0
+}
+
+// inputSequenceRune checks whether the pressed rune is the next one of the
+// sequence. Typing a rune in the wrong order is an invalid key press and
+// the sequence of the current round has to be typed from the start again.
+func (s *Session) inputSequenceRune(pressed rune) {
+	//The player has to wait until the whole sequence of this round has
+	//been hidden.
+	if len(s.indicesToHide) > 0 {
+		return
+	}
+
+	expected := s.gameBoard[s.sequence[s.sequenceProgress]]
+	if expected.Character != pressed {
+		for _, index := range s.sequence[:s.sequenceProgress] {
+			s.gameBoard[index].State = Hidden
+		}
+		s.sequenceProgress = 0
+		s.pressWrongKey(pressed)
+		s.updateGameState()
+		return
+	}
+
+	//The reaction time and speed bonus only count the first time a cell
+	//is revealed in a round, otherwise retrying would pay off.
+	if s.sequenceProgress < s.sequenceRevealed {
+		expected.State = Guessed
+	} else {
+		s.guess(expected)
+		s.sequenceRevealed++
+	}
+	s.sequenceProgress++
+	//The last round ends the game, which is decided by updateGameState.
+	if s.sequenceProgress == s.round && s.round < len(s.gameBoard) {
+		s.previousRoundsGuessedCount += s.round
+		s.round++
+		s.prepareSequenceRound()
+		s.nextHide = s.clock.Now().Add(s.difficulty.HideTimes)
+		s.record(&RecordedEvent{Kind: RoundEvent, Round: s.round})
+		s.wake()
+	}
+	s.updateGameState()
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSequenceMode(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "sequence",
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		RowCount:                3,
+		ColumnCount:             2,
+		HideTimes:               time.Second,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '6'),
+		},
+	}
+
+	//Without a start delay, each cell is hidden one second after the
+	//previous one or after the start of the round.
+	testClock := NewManualClock(time.Time{})
+	session := newTestSession(t, testDifficulty, SequenceMode, 1, testClock)
+	updates := session.Subscribe()
+	session.Start(context.Background())
+
+	//hiddenInOrder waits for the given amount of cells to be hidden and
+	//returns their characters in the order they were hidden.
+	var order []rune
+	hiddenInOrder := func(count int) []rune {
+		for len(order) < count {
+			waitForTimers(t, testClock, 1)
+			testClock.Advance(time.Second)
+			select {
+			case <-updates:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("cell %d wasn't hidden", len(order)+1)
+			}
+
+			snapshot := session.Snapshot()
+			for _, cell := range snapshot.Board {
+				if cell.State == Hidden && !containsRune(order, cell.Character) {
+					order = append(order, cell.Character)
+				}
+			}
+		}
+		return order[:count]
+	}
+
+	for round := 1; round <= 6; round++ {
+		sequence := hiddenInOrder(round)
+		if snapshot := session.Snapshot(); snapshot.HiddenCellCount != round || snapshot.Round != round {
+			t.Fatalf("expected %d hidden cells in round %d, got %d in round %d",
+				round, round, snapshot.HiddenCellCount, snapshot.Round)
+		}
+
+		//A wrong order has to be typed from the start again.
+		if round == 3 {
+			session.PressRune(sequence[0])
+			session.PressRune(sequence[2])
+			if snapshot := session.Snapshot(); snapshot.SequenceProgress != 0 || snapshot.InvalidKeyPresses != 1 {
+				t.Fatalf("expected reset sequence and one invalid key press, got %d and %d",
+					snapshot.SequenceProgress, snapshot.InvalidKeyPresses)
+			}
+		}
+
+		for _, char := range sequence {
+			session.PressRune(char)
+		}
+		order = nil
+	}
+
+	if snapshot := session.Snapshot(); snapshot.State != Victory || snapshot.Score != 21*5-2 {
+		t.Errorf("expected victory with 103 points, got %s with %d points", snapshot.State, snapshot.Score)
+	}
+}
+
+func containsRune(runes []rune, wanted rune) bool {
+	for _, r := range runes {
+		if r == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+// TestSequenceRetry makes sure that retyping a sequence after a wrong key
+// press doesn't record reaction times or speed bonuses twice.
+func TestSequenceRetry(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "sequence",
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		SpeedBonus:              8,
+		SpeedBonusHalfLife:      time.Second,
+		RowCount:                3,
+		ColumnCount:             2,
+		HideTimes:               time.Second,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '6'),
+		},
+	}
+
+	testClock := NewManualClock(time.Time{})
+	session := newTestSession(t, testDifficulty, SequenceMode, 1, testClock)
+	hidden := func() []rune {
+		var characters []rune
+		for _, cell := range session.Snapshot().Board {
+			if cell.State == Hidden {
+				characters = append(characters, cell.Character)
+			}
+		}
+		return characters
+	}
+
+	//The first round only consists of a single cell.
+	session.Tick()
+	session.PressRune(hidden()[0])
+	if snapshot := session.Snapshot(); snapshot.Round != 2 {
+		t.Fatalf("expected the second round, got round %d", snapshot.Round)
+	}
+
+	session.Tick()
+	session.Tick()
+	sequence := session.Snapshot().Board
+	first, second := sequence[session.sequence[0]].Character, sequence[session.sequence[1]].Character
+	session.PressRune(first)
+	//Wrong order, so the round has to be typed from the start again.
+	session.PressRune(first)
+	session.PressRune(first)
+	session.PressRune(second)
+
+	snapshot := session.Snapshot()
+	if len(snapshot.ReactionTimes) != 3 {
+		t.Errorf("expected three reaction times, got %v", snapshot.ReactionTimes)
+	}
+	if snapshot.InvalidKeyPresses != 1 {
+		t.Errorf("expected one invalid key press, got %d", snapshot.InvalidKeyPresses)
+	}
+	//All cells have been guessed instantly, so each of them gets the whole
+	//bonus once.
+	if snapshot.ScoreBreakdown.SpeedBonus != 3*8 {
+		t.Errorf("expected a speed bonus of %d, got %d", 3*8, snapshot.ScoreBreakdown.SpeedBonus)
+	}
+}
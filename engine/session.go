@@ -0,0 +1,926 @@
+// Package engine contains the rules of memoryalike. It doesn't know about
+// terminals or any other kind of user interface, therefore it can be used
+// for building different front ends, bots or analytics on top of it.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// CellState describes what the player currently sees of a cell.
+type CellState int
+
+const (
+	Shown CellState = iota
+	Hidden
+	Guessed
+	//Revealed is used for cells that the player has uncovered in pairs
+	//mode, but that haven't been matched yet.
+	Revealed
+)
+
+// Cell represents a single character visible to the user.
+type Cell struct {
+	Character rune
+	State     CellState
+	//HideOrder is the position at which the cell has been hidden during
+	//the current round, starting at 1. Cells that haven't been hidden yet
+	//and cells in pairs mode, which are all hidden at once, have 0.
+	HideOrder int
+
+	//hiddenAt is the playing time at which the cell has been hidden.
+	hiddenAt time.Duration
+}
+
+// State describes whether a session is still running and how it ended.
+type State int
+
+const (
+	Ongoing State = iota
+	GameOver
+	Victory
+)
+
+func (state State) String() string {
+	switch state {
+	case Ongoing:
+		return "ongoing"
+	case GameOver:
+		return "game over"
+	case Victory:
+		return "victory"
+	}
+
+	return "unknown"
+}
+
+// EndReason describes which rule has ended a session.
+type EndReason int
+
+const (
+	//NotEnded is used for sessions that are still ongoing.
+	NotEnded EndReason = iota
+	//TooManyHidden means that the hidden cell limit of the difficulty has
+	//been reached.
+	TooManyHidden
+	//ScoreTooLow means that all cells have been guessed, but the score is
+	//below the difficulty's minimum winning score.
+	ScoreTooLow
+	//AllGuessed means that all cells have been guessed with a sufficient
+	//score.
+	AllGuessed
+	//Surrendered means that the player has given up.
+	Surrendered
+	//Abandoned means that the session has been ended without the player
+	//giving up, for example by restarting.
+	Abandoned
+)
+
+// PauseReason is a flag describing why a session is paused. A session can
+// be paused for multiple reasons at once.
+type PauseReason int
+
+const (
+	PausedByPlayer PauseReason = 1 << iota
+	//PausedByScreenSize is used while the board doesn't fit on the screen.
+	PausedByScreenSize
+)
+
+// Mode decides what happens once all cells have been guessed.
+type Mode int
+
+const (
+	//ClassicMode ends the game once all cells have been guessed.
+	ClassicMode Mode = iota
+	//EndlessMode starts a new, harder round whenever all cells have been
+	//guessed. The game only ends by losing.
+	EndlessMode
+	//PairsMode contains each rune twice. After a preview, all cells are
+	//hidden and the player has to find the pairs by revealing two cells at
+	//a time.
+	PairsMode
+	//SequenceMode hides the cells one after another, starting with one
+	//cell in the first round and adding one per round. The player has to
+	//type the hidden characters in the order they were hidden.
+	SequenceMode
+	//HotSeatMode is played by two players sharing one keyboard, using the
+	//rules of classic mode. The players take turns, each key press being
+	//a turn. Guesses and penalties count for whoever typed the key.
+	HotSeatMode
+)
+
+// Modes contains all modes in the order they should be presented to users.
+var Modes = []Mode{ClassicMode, EndlessMode, PairsMode, SequenceMode, HotSeatMode}
+
+func (mode Mode) String() string {
+	switch mode {
+	case ClassicMode:
+		return "classic"
+	case EndlessMode:
+		return "endless"
+	case PairsMode:
+		return "pairs"
+	case SequenceMode:
+		return "sequence"
+	case HotSeatMode:
+		return "hot-seat"
+	}
+
+	return "unknown"
+}
+
+// ParseMode returns the mode with the given name, as returned by
+// Mode.String.
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range Modes {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+
+	return ClassicMode, fmt.Errorf("unknown game mode '%s'", name)
+}
+
+// Session represents all game state for a single game. All exported
+// methods are safe for concurrent use.
+type Session struct {
+	mutex *sync.Mutex
+	//subscribers are notified whenever the session has changed.
+	subscribers []chan struct{}
+
+	state     State
+	endReason EndReason
+	mode      Mode
+	//round is the number of the current round, starting at 1. Only endless
+	//games have more than one round.
+	round int
+	//previousRoundsGuessedCount is the amount of cells guessed correctly in
+	//all rounds before the current one.
+	previousRoundsGuessedCount int
+
+	score int
+	//invalidKeyPresses counts the invalid keyPresses made by the player.
+	//This only tracks runes, not stuff like CTRL, ArrowUp ...
+	invalidKeyPresses int
+	//wrongKeys counts how often each rune has been pressed by mistake.
+	wrongKeys map[rune]int
+	//reactionTimes contains the time between hiding and guessing a cell,
+	//one per guessed cell.
+	reactionTimes []time.Duration
+	//speedBonus is the sum of the bonuses for quick guesses. Unlike the
+	//rest of the score, it can't be derived from the board.
+	speedBonus int
+	breakdown  ScoreBreakdown
+
+	gameBoard     []*Cell
+	indicesToHide []int
+	//hiddenCount is the amount of cells hidden during the current round.
+	hiddenCount int
+
+	difficulty *Difficulty
+
+	//seed is the seed used for random, which allows reproducing the board
+	//and the order in which the cells are hidden.
+	seed   int64
+	random *rand.Rand
+
+	//clock is used for all timing related logic, such as hiding runes.
+	clock     Clock
+	startTime time.Time
+	//nextHide is the point in time at which the next rune will be hidden.
+	nextHide time.Time
+	//pauseReasons prevents runes from being hidden as long as any reason
+	//is set. While paused, nextHide isn't valid, instead untilNextHide holds
+	//the time left until the next hide.
+	pauseReasons  PauseReason
+	untilNextHide time.Duration
+	//pausedAt is the point in time the current pause has started at.
+	pausedAt time.Time
+	//pausedDuration is the sum of all finished pauses. It's excluded from
+	//the session's duration.
+	pausedDuration time.Duration
+	//wakeUp is closed once a paused session resumes or new runes can be
+	//hidden, waking up the rune hiding coroutine.
+	wakeUp chan struct{}
+	//endTime is the point in time at which the state changed from ongoing
+	//to either gameOver or victory.
+	endTime time.Time
+
+	//pendingColumn is the column letter typed in pairs mode, waiting for
+	//the row to be typed. Zero means no column has been typed.
+	pendingColumn rune
+	//revealed contains the indices of the cells revealed in pairs mode.
+	revealed []int
+
+	//sequence is the order in which cells are hidden in sequence mode.
+	sequence []int
+	//sequenceProgress is the amount of cells of the current round's
+	//sequence that the player has already typed correctly.
+	sequenceProgress int
+	//sequenceRevealed is the amount of cells of the current round's
+	//sequence that have been revealed at least once. Retyping them after a
+	//wrong key press doesn't score again.
+	sequenceRevealed int
+
+	//players contains the score of each player in hot-seat mode and is
+	//nil in all other modes.
+	players []PlayerScore
+	//currentPlayer is the index of the player whose turn it is.
+	currentPlayer int
+
+	//recording contains all events of this session, allowing it to be
+	//replayed later on.
+	recording *Recording
+}
+
+// Snapshot is a copy of a session's state at a certain point in time. It
+// can be read without having to worry about concurrent changes.
+type Snapshot struct {
+	State State
+	//EndReason is the rule that has ended the game.
+	EndReason EndReason
+	Mode      Mode
+	//Round is the number of the current round, starting at 1.
+	Round int
+
+	Score int
+	//ScoreBreakdown contains the parts the score is made up of.
+	ScoreBreakdown    ScoreBreakdown
+	InvalidKeyPresses int
+	//WrongKeys counts how often each rune has been pressed by mistake.
+	//Pairs that don't match count as invalid key presses, but not as
+	//wrong keys.
+	WrongKeys map[rune]int
+	//ReactionTimes contains the playing time between hiding and guessing
+	//a cell, one per cell guessed in classic, endless or sequence mode.
+	ReactionTimes []time.Duration
+
+	//Board contains the cells row by row.
+	Board []Cell
+	//CellsLeftToHide is the amount of cells that haven't been hidden yet.
+	CellsLeftToHide int
+	HiddenCellCount int
+	//HiddenCellLimit is the smallest amount of hidden cells that loses the
+	//game. It is 0 if the game can't be lost by hiding cells.
+	HiddenCellLimit int
+
+	//Difficulty is the difficulty of the current round. It must not be
+	//modified.
+	Difficulty *Difficulty
+	Seed       int64
+
+	//PendingColumn is the column letter typed in pairs mode. Zero means
+	//that no column has been typed yet.
+	PendingColumn rune
+	//SequenceProgress is the amount of cells of the current round that
+	//have been typed in the correct order in sequence mode.
+	SequenceProgress int
+	//Players contains the score of each player in hot-seat mode. The
+	//session's score is the sum of them.
+	Players []PlayerScore
+	//CurrentPlayer is the index of the player whose turn it is in
+	//hot-seat mode.
+	CurrentPlayer int
+
+	Paused bool
+	//Duration is the time played so far, excluding pauses.
+	Duration time.Duration
+	//EndTime is only set once the game is over.
+	EndTime time.Time
+}
+
+// RoundsSurvived returns the amount of rounds that have been completed.
+func (snapshot Snapshot) RoundsSurvived() int {
+	return snapshot.Round - 1
+}
+
+// NewSession produces a ready-to-use session state. Runes are only hidden
+// once Start has been called or by calling Tick manually. Two sessions
+// using the same difficulty, mode and seed will have the same board and
+// hide order. If the difficulty can't be played in the given mode, an
+// error is returned.
+func NewSession(difficulty *Difficulty, mode Mode, seed int64, clock Clock) (*Session, error) {
+	if validationError := difficulty.Validate(); validationError != nil {
+		return nil, validationError
+	}
+	if modeError := difficulty.ValidateMode(mode); modeError != nil {
+		return nil, modeError
+	}
+
+	startTime := clock.Now()
+	session := &Session{
+		mutex: &sync.Mutex{},
+
+		state: Ongoing,
+		mode:  mode,
+		round: 1,
+
+		difficulty: difficulty,
+
+		seed:   seed,
+		random: rand.New(rand.NewSource(seed)),
+
+		clock:     clock,
+		startTime: startTime,
+		nextHide:  startTime.Add(difficulty.StartDelay + difficulty.HideTimes),
+
+		recording: &Recording{
+			Seed:       seed,
+			Mode:       mode.String(),
+			Difficulty: NewDifficultyDefinition(difficulty),
+			StartTime:  startTime,
+		},
+	}
+	//The recording keeps the original difficulty, as the board will be
+	//adjusted the same way when replaying.
+	if mode == PairsMode {
+		session.difficulty = pairsDifficulty(difficulty)
+	}
+	if fillError := session.fillGameBoard(); fillError != nil {
+		return nil, fillError
+	}
+	if mode == SequenceMode {
+		session.startSequence()
+	}
+	if mode == HotSeatMode {
+		session.startHotSeat()
+	}
+
+	return session, nil
+}
+
+// Subscribe returns a channel that receives a value whenever the session
+// has changed. Notifications are coalesced, so a slow subscriber only
+// misses intermediate changes, but never the latest one. The channel is
+// closed once the session has ended, as nothing changes afterwards.
+func (s *Session) Subscribe() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	subscriber := make(chan struct{}, 1)
+	if s.state != Ongoing {
+		close(subscriber)
+	} else {
+		s.subscribers = append(s.subscribers, subscriber)
+	}
+	return subscriber
+}
+
+// notify informs all subscribers about a change without ever blocking.
+func (s *Session) notify() {
+	for _, subscriber := range s.subscribers {
+		select {
+		case subscriber <- struct{}{}:
+		default:
+			//A notification is already pending.
+		}
+
+		if s.state != Ongoing {
+			close(subscriber)
+		}
+	}
+
+	if s.state != Ongoing {
+		s.subscribers = nil
+	}
+}
+
+// Snapshot returns a copy of the session's current state.
+func (s *Session) Snapshot() Snapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	board := make([]Cell, 0, len(s.gameBoard))
+	for _, cell := range s.gameBoard {
+		board = append(board, *cell)
+	}
+	_, hiddenCellCount, _ := s.countCells()
+	wrongKeys := make(map[rune]int, len(s.wrongKeys))
+	for key, count := range s.wrongKeys {
+		wrongKeys[key] = count
+	}
+
+	return Snapshot{
+		State:     s.state,
+		EndReason: s.endReason,
+		Mode:      s.mode,
+		Round:     s.round,
+
+		Score:             s.score,
+		ScoreBreakdown:    s.breakdown,
+		InvalidKeyPresses: s.invalidKeyPresses,
+		WrongKeys:         wrongKeys,
+		ReactionTimes:     append([]time.Duration(nil), s.reactionTimes...),
+
+		Board:           board,
+		CellsLeftToHide: len(s.indicesToHide),
+		HiddenCellCount: hiddenCellCount,
+		HiddenCellLimit: s.hiddenCellLimit(),
+
+		Difficulty: s.difficulty,
+		Seed:       s.seed,
+
+		PendingColumn:    s.pendingColumn,
+		SequenceProgress: s.sequenceProgress,
+		Players:          append([]PlayerScore(nil), s.players...),
+		CurrentPlayer:    s.currentPlayer,
+
+		Paused:   s.isPaused(),
+		Duration: s.duration(),
+		EndTime:  s.endTime,
+	}
+}
+
+// Recording returns a copy of all events that have happened so far.
+func (s *Session) Recording() *Recording {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	recordingCopy := *s.recording
+	recordingCopy.Events = append([]*RecordedEvent(nil), s.recording.Events...)
+	return &recordingCopy
+}
+
+// fillGameBoard creates a new board and hide order according to the
+// session's difficulty. An error is returned if the rune pools don't
+// contain enough characters for the board.
+func (s *Session) fillGameBoard() error {
+	s.hiddenCount = 0
+	if s.mode == PairsMode {
+		return s.fillPairsBoard()
+	}
+
+	characterSet, charSetError := CharacterSet(s.random, s.difficulty.RowCount*s.difficulty.ColumnCount, s.difficulty.RunePools...)
+	if charSetError != nil {
+		return charSetError
+	}
+	s.gameBoard = make([]*Cell, 0, len(characterSet))
+	for _, char := range characterSet {
+		s.gameBoard = append(s.gameBoard, &Cell{Character: char, State: Shown})
+	}
+
+	//This decides which cells will be hidden in which order. If this stack
+	//is empty, the game is over.
+	s.indicesToHide = make([]int, len(s.gameBoard))
+	for i := 0; i < len(s.indicesToHide); i++ {
+		s.indicesToHide[i] = i
+	}
+	s.random.Shuffle(len(s.indicesToHide), func(a, b int) {
+		s.indicesToHide[a], s.indicesToHide[b] = s.indicesToHide[b], s.indicesToHide[a]
+	})
+	return nil
+}
+
+// Start starts a goroutine that hides one rune on the gameboard each X
+// milliseconds. X is defined by the hidingTime defined in the referenced
+// difficulty of the session. The first rune is hidden after the start
+// delay plus one hiding time. While there are no characters left to hide,
+// the coroutine waits for a new round to start. Once the game has ended or
+// the context has been cancelled, this coroutine exits. Cancelling doesn't
+// end the session, it only stops hiding runes.
+func (s *Session) Start(ctx context.Context) {
+	go func() {
+		for {
+			s.mutex.Lock()
+			untilNextHide := s.nextHide.Sub(s.clock.Now())
+			s.mutex.Unlock()
+
+			timer := s.clock.NewTimer(untilNextHide)
+			select {
+			case <-timer.Channel():
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+
+			s.mutex.Lock()
+			if s.state != Ongoing {
+				s.mutex.Unlock()
+				return
+			}
+
+			//Once all runes of a round have been hidden, we wait for the
+			//next round to start.
+			if s.isPaused() || len(s.indicesToHide) == 0 {
+				wakeUp := s.wakeUpChannel()
+				s.mutex.Unlock()
+				select {
+				case <-wakeUp:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			//The next hide might have been postponed while we were waiting,
+			//for example because a new round has started.
+			if s.clock.Now().Before(s.nextHide) {
+				s.mutex.Unlock()
+				continue
+			}
+
+			s.hideRune()
+			//The deadlines are calculated from the previous one, so that the
+			//time spent on hiding doesn't delay the following hides.
+			s.nextHide = s.nextHide.Add(s.difficulty.HideTimes)
+			s.notify()
+			s.mutex.Unlock()
+		}
+	}()
+}
+
+// Tick hides the next rune right away. This is meant for front ends that
+// drive the timing themselves instead of calling Start.
+func (s *Session) Tick() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != Ongoing {
+		return
+	}
+
+	s.hideRune()
+	s.notify()
+}
+
+// hideRune hides a rune that's currently visible on the gameboard.
+func (s *Session) hideRune() {
+	//In pairs mode, all cells are hidden at once after the preview.
+	if s.mode == PairsMode {
+		if len(s.indicesToHide) > 0 {
+			s.endPreview()
+		}
+		return
+	}
+
+	nextIndexToHide := len(s.indicesToHide) - 1
+	if nextIndexToHide != -1 {
+		s.record(&RecordedEvent{Kind: HideEvent, Index: s.indicesToHide[nextIndexToHide]})
+		s.hiddenCount++
+		cell := s.gameBoard[s.indicesToHide[nextIndexToHide]]
+		cell.State = Hidden
+		cell.HideOrder = s.hiddenCount
+		cell.hiddenAt = s.duration()
+		s.indicesToHide = s.indicesToHide[:len(s.indicesToHide)-1]
+		s.updateGameState()
+	}
+}
+
+// PressRune checks the pressed rune for possible matches and updates the
+// session accordingly. Meaning that if a match between a hidden cell, it's
+// underlying character and the input rune is found, the player gets a
+// point.
+func (s *Session) PressRune(pressed rune) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.inputRunePress(pressed)
+	s.notify()
+}
+
+func (s *Session) inputRunePress(pressed rune) {
+	//Game is already over. All further checks are unnecessary.
+	if s.state != Ongoing {
+		return
+	}
+
+	//While paused, the player can't see the board, so input is ignored.
+	if s.isPaused() {
+		return
+	}
+
+	s.record(&RecordedEvent{Kind: RunePressEvent, Rune: string(pressed)})
+	if s.mode == PairsMode {
+		s.inputPairsRune(pressed)
+		return
+	}
+	if s.mode == SequenceMode {
+		s.inputSequenceRune(pressed)
+		return
+	}
+
+	//In hot-seat mode, the next key press belongs to the other player, no
+	//matter whether this one was correct.
+	defer s.passTurn()
+
+	for _, cell := range s.gameBoard {
+		if cell.Character == pressed {
+			if cell.State == Hidden {
+				s.guess(cell)
+				s.updateGameState()
+				return
+			}
+
+			break
+		}
+	}
+
+	//Pressed rune wasn't hidden or wasn't present, therefore the user gets
+	//minus points
+	s.pressWrongKey(pressed)
+	s.updateGameState()
+}
+
+// guess marks a hidden cell as guessed and remembers how long it took the
+// player to guess it.
+func (s *Session) guess(cell *Cell) {
+	cell.State = Guessed
+	reactionTime := s.duration() - cell.hiddenAt
+	s.reactionTimes = append(s.reactionTimes, reactionTime)
+	speedBonus := s.difficulty.SpeedBonusFor(reactionTime)
+	s.speedBonus += speedBonus
+	if s.players != nil {
+		s.players[s.currentPlayer].GuessedCount++
+		s.players[s.currentPlayer].speedBonus += speedBonus
+	}
+}
+
+// pressWrongKey counts a key press that doesn't match any cell as invalid.
+func (s *Session) pressWrongKey(pressed rune) {
+	s.invalidKeyPresses++
+	if s.wrongKeys == nil {
+		s.wrongKeys = make(map[rune]int)
+	}
+	s.wrongKeys[pressed]++
+	if s.players != nil {
+		s.players[s.currentPlayer].InvalidKeyPresses++
+	}
+}
+
+// updateGameState determines whether the game is over and what the players
+// score is.
+func (s *Session) updateGameState() {
+	//Game is already over. All further checks are unnecessary.
+	if s.state != Ongoing {
+		return
+	}
+
+	guessedCellCount, hiddenCellCount, shownCellCount := s.countCells()
+
+	//In pairs mode, the points are given per pair instead of per cell.
+	scoringCount := s.previousRoundsGuessedCount + guessedCellCount
+	if s.mode == PairsMode {
+		scoringCount /= 2
+	}
+	s.breakdown = ScoreBreakdown{
+		Base:       scoringCount * s.difficulty.CorrectGuessPoints,
+		SpeedBonus: s.speedBonus,
+		Penalty:    s.invalidKeyPresses * s.difficulty.InvalidKeyPressPenality,
+	}
+	s.score = s.breakdown.Total()
+	s.updatePlayerScores()
+
+	if s.isHiddenLimitReached(hiddenCellCount) {
+		s.end(GameOver, TooManyHidden)
+	} else if shownCellCount == 0 && hiddenCellCount == 0 && s.mode == EndlessMode {
+		s.startNextRound()
+	} else if shownCellCount == 0 && hiddenCellCount == 0 {
+		//The game is only over if all cells have been guessed correctly
+
+		//Even if all cells have been guessed correctly, a low score is
+		//deemed a loss, as defined by the difficulty.
+		if s.score < s.difficulty.MinimumWinningScore {
+			s.end(GameOver, ScoreTooLow)
+		} else {
+			s.end(Victory, AllGuessed)
+		}
+	}
+}
+
+// countCells returns the amount of cells in each state.
+func (s *Session) countCells() (guessedCellCount, hiddenCellCount, shownCellCount int) {
+	for _, cell := range s.gameBoard {
+		if cell.State == Hidden {
+			hiddenCellCount++
+		} else if cell.State == Guessed {
+			guessedCellCount++
+		} else {
+			shownCellCount++
+		}
+	}
+
+	return
+}
+
+// isHiddenLimitReached decides whether the given amount of hidden cells
+// loses the game. The limits are defined by the difficulty, either relative
+// to the board size, as an absolute count or both. In case of a normal game
+// for example, the player loses once 40 percent, meaning 4 cells, are
+// hidden.
+func (s *Session) isHiddenLimitReached(hiddenCellCount int) bool {
+	//In pairs and sequence mode, cells are hidden on purpose.
+	if hiddenCellCount == 0 || s.mode == PairsMode || s.mode == SequenceMode {
+		return false
+	}
+
+	if s.difficulty.MaxHiddenCount > 0 && hiddenCellCount >= s.difficulty.MaxHiddenCount {
+		return true
+	}
+
+	return s.difficulty.MaxHiddenRatio > 0 &&
+		float64(hiddenCellCount)/float64(len(s.gameBoard)) >= s.difficulty.MaxHiddenRatio
+}
+
+// hiddenCellLimit returns the smallest amount of hidden cells that loses
+// the game. If the game can't be lost by hiding cells, 0 is returned.
+func (s *Session) hiddenCellLimit() int {
+	for hiddenCellCount := 1; hiddenCellCount <= len(s.gameBoard); hiddenCellCount++ {
+		if s.isHiddenLimitReached(hiddenCellCount) {
+			return hiddenCellCount
+		}
+	}
+
+	return 0
+}
+
+// Pause stops the hiding of runes until Resume is called for all given
+// reasons. The time left until the next rune would've been hidden is kept.
+func (s *Session) Pause(reasons PauseReason) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pause(reasons) {
+		s.notify()
+	}
+}
+
+// pause adds the given reasons for the session being paused. It returns
+// whether any reason has been added.
+func (s *Session) pause(reasons PauseReason) bool {
+	if s.state != Ongoing || s.pauseReasons&reasons == reasons {
+		return false
+	}
+
+	if !s.isPaused() {
+		now := s.clock.Now()
+		s.pausedAt = now
+		s.untilNextHide = s.nextHide.Sub(now)
+		s.record(&RecordedEvent{Kind: PauseEvent})
+	}
+	s.pauseReasons |= reasons
+	return true
+}
+
+// Resume removes the given reasons for the session being paused. Once no
+// reasons are left, runes are hidden again.
+func (s *Session) Resume(reasons PauseReason) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.resume(reasons) {
+		s.notify()
+	}
+}
+
+// resume removes the given reasons for the session being paused. It
+// returns whether any reason has been removed.
+func (s *Session) resume(reasons PauseReason) bool {
+	if s.pauseReasons&reasons == 0 {
+		return false
+	}
+
+	s.pauseReasons &^= reasons
+	if !s.isPaused() {
+		now := s.clock.Now()
+		s.pausedDuration += now.Sub(s.pausedAt)
+		s.nextHide = now.Add(s.untilNextHide)
+		s.wake()
+		s.record(&RecordedEvent{Kind: ResumeEvent})
+	}
+	return true
+}
+
+// wakeUpChannel returns a channel that is closed the next time wake is
+// called.
+func (s *Session) wakeUpChannel() <-chan struct{} {
+	if s.wakeUp == nil {
+		s.wakeUp = make(chan struct{})
+	}
+	return s.wakeUp
+}
+
+// wake wakes up the rune hiding coroutine, in case it's waiting for the
+// session to be resumed or for new runes to hide.
+func (s *Session) wake() {
+	if s.wakeUp != nil {
+		close(s.wakeUp)
+		s.wakeUp = nil
+	}
+}
+
+// TogglePause pauses the session for the given reason or resumes it, if
+// it has already been paused for that reason.
+func (s *Session) TogglePause(reason PauseReason) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var changed bool
+	if s.pauseReasons&reason != 0 {
+		changed = s.resume(reason)
+	} else {
+		changed = s.pause(reason)
+	}
+	if changed {
+		s.notify()
+	}
+}
+
+// isPaused indicates whether the session is currently paused for any
+// reason.
+func (s *Session) isPaused() bool {
+	return s.pauseReasons != 0
+}
+
+// startNextRound makes the game harder and fills the board with new runes.
+// The score is kept, as it's carried over to the next round.
+func (s *Session) startNextRound() {
+	s.previousRoundsGuessedCount += len(s.gameBoard)
+	s.round++
+	previous := s.difficulty
+	s.difficulty = nextEndlessDifficulty(previous, s.round)
+	//The board only grows as long as the rune pools suffice, but in case
+	//they don't, the board of the previous round is reused, which has
+	//been filled before.
+	if s.fillGameBoard() != nil {
+		s.difficulty = previous
+		s.fillGameBoard()
+	}
+	//The new round starts right away, but we don't want to hide a rune
+	//before the player had the chance to look at the new board.
+	s.nextHide = s.clock.Now().Add(s.difficulty.HideTimes)
+	s.record(&RecordedEvent{Kind: RoundEvent, Round: s.round})
+	s.wake()
+}
+
+// end finishes the session with the given state. Calling this on a session
+// that has already ended has no effect.
+func (s *Session) end(state State, reason EndReason) {
+	if s.state != Ongoing {
+		return
+	}
+
+	//Makes sure that the current pause isn't counted as playing time.
+	s.resume(s.pauseReasons)
+	s.state = state
+	s.endReason = reason
+	s.endTime = s.clock.Now()
+	s.record(&RecordedEvent{Kind: StateChangeEvent, State: state.String()})
+	//The rune hiding coroutine might be waiting for runes to hide, but it
+	//has to exit now.
+	s.wake()
+}
+
+// Surrender ends the game as lost, if it's still ongoing.
+func (s *Session) Surrender() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.surrender()
+	s.notify()
+}
+
+func (s *Session) surrender() {
+	if s.state != Ongoing {
+		return
+	}
+
+	s.record(&RecordedEvent{Kind: SurrenderEvent})
+	s.end(GameOver, Surrendered)
+}
+
+// Abandon ends the session, for example because the player has restarted
+// the game. The session is deemed lost, but abandoned sessions are left out
+// of the high scores and statistics.
+func (s *Session) Abandon() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.end(GameOver, Abandoned)
+	s.notify()
+}
+
+// record adds the event to the session's recording. The event's offset is
+// set to the current point in time.
+func (s *Session) record(event *RecordedEvent) {
+	event.Offset = s.clock.Now().Sub(s.startTime)
+	s.recording.Events = append(s.recording.Events, event)
+}
+
+// duration returns how long the session has been running. For finished
+// sessions, this is the time it took until the game ended. Time spent
+// paused isn't counted.
+func (s *Session) duration() time.Duration {
+	if s.state != Ongoing {
+		return s.endTime.Sub(s.startTime) - s.pausedDuration
+	}
+
+	duration := s.clock.Now().Sub(s.startTime) - s.pausedDuration
+	if s.isPaused() {
+		duration -= s.clock.Now().Sub(s.pausedAt)
+	}
+	return duration
+}
@@ -0,0 +1,641 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type guessType int
+
+const (
+	none guessType = iota
+	anyhiddenRune
+	anyShownRune
+	nonExistantRune
+)
+
+type stateIteration struct {
+	hideRune                  bool
+	input                     guessType
+	expectedScore             int
+	expectedInvalidKeyPresses int
+	expectedGameState         State
+}
+
+// TestState tests the gamestate as a whole. E.g. simulating user interaction
+// and seeing whether the results are as expected.
+func TestState(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "easy",
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		RowCount:                3,
+		ColumnCount:             2,
+		HideTimes:               time.Second,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '6'),
+		},
+	}
+
+	t.Run("No input gameover due to 50% hidden fields", func(t *testing.T) {
+		iterations := []stateIteration{
+			{false, none, 0, 0, Ongoing},
+			{true, none, 0, 0, Ongoing},
+			{true, none, 0, 0, Ongoing},
+			{true, none, 0, 0, GameOver},
+		}
+		state := newTestSession(t, testDifficulty, ClassicMode, 1, NewManualClock(time.Time{}))
+		runIterations(t, iterations, state)
+	})
+
+	t.Run("invalid input without hidden fields", func(t *testing.T) {
+		iterations := []stateIteration{
+			{false, nonExistantRune, -2, 1, Ongoing},
+			{false, nonExistantRune, -4, 2, Ongoing},
+			{false, nonExistantRune, -6, 3, Ongoing},
+			{false, nonExistantRune, -8, 4, Ongoing},
+		}
+		state := newTestSession(t, testDifficulty, ClassicMode, 1, NewManualClock(time.Time{}))
+		runIterations(t, iterations, state)
+	})
+
+	t.Run("valid input without hidden fields", func(t *testing.T) {
+		iterations := []stateIteration{
+			{false, anyShownRune, -2, 1, Ongoing},
+			{false, anyShownRune, -4, 2, Ongoing},
+			{false, anyShownRune, -6, 3, Ongoing},
+			{false, anyShownRune, -8, 4, Ongoing},
+		}
+		state := newTestSession(t, testDifficulty, ClassicMode, 1, NewManualClock(time.Time{}))
+		runIterations(t, iterations, state)
+	})
+
+	t.Run("all guesses correct", func(t *testing.T) {
+		iterations := []stateIteration{
+			{true, anyhiddenRune, 5, 0, Ongoing},
+			{true, anyhiddenRune, 10, 0, Ongoing},
+			{true, anyhiddenRune, 15, 0, Ongoing},
+			{true, anyhiddenRune, 20, 0, Ongoing},
+			{true, anyhiddenRune, 25, 0, Ongoing},
+			{true, anyhiddenRune, 30, 0, Victory},
+		}
+		state := newTestSession(t, testDifficulty, ClassicMode, 1, NewManualClock(time.Time{}))
+		runIterations(t, iterations, state)
+	})
+
+	t.Run("one incorrect guess no gameover", func(t *testing.T) {
+		iterations := []stateIteration{
+			{true, anyhiddenRune, 5, 0, Ongoing},
+			{true, anyhiddenRune, 10, 0, Ongoing},
+			{true, anyhiddenRune, 15, 0, Ongoing},
+			{true, nonExistantRune, 13, 1, Ongoing},
+			{true, anyhiddenRune, 18, 1, Ongoing},
+			{true, anyhiddenRune, 23, 1, Ongoing},
+		}
+		state := newTestSession(t, testDifficulty, ClassicMode, 1, NewManualClock(time.Time{}))
+		runIterations(t, iterations, state)
+	})
+
+	t.Run("one incorrect guess with victory", func(t *testing.T) {
+		iterations := []stateIteration{
+			{true, anyhiddenRune, 5, 0, Ongoing},
+			{true, anyhiddenRune, 10, 0, Ongoing},
+			{true, anyhiddenRune, 15, 0, Ongoing},
+			{true, nonExistantRune, 13, 1, Ongoing},
+			{true, anyhiddenRune, 18, 1, Ongoing},
+			{true, anyhiddenRune, 23, 1, Ongoing},
+			{false, anyhiddenRune, 28, 1, Victory},
+		}
+		state := newTestSession(t, testDifficulty, ClassicMode, 1, NewManualClock(time.Time{}))
+		runIterations(t, iterations, state)
+	})
+}
+
+func runIterations(t *testing.T, iterations []stateIteration, state *Session) {
+	for _, iteration := range iterations {
+		if iteration.hideRune {
+			state.hideRune()
+		}
+
+		switch iteration.input {
+		case anyhiddenRune:
+			for _, cell := range state.gameBoard {
+				if cell.State == Hidden {
+					state.inputRunePress(cell.Character)
+					break
+				}
+			}
+		case anyShownRune:
+			for _, cell := range state.gameBoard {
+				if cell.State == Shown {
+					state.inputRunePress(cell.Character)
+					break
+				}
+			}
+		case nonExistantRune:
+			state.inputRunePress('-')
+		}
+
+		if iteration.expectedInvalidKeyPresses != state.invalidKeyPresses {
+			t.Errorf("Invalid keypresses %d, expected %d", state.invalidKeyPresses, iteration.expectedInvalidKeyPresses)
+		}
+
+		if iteration.expectedScore != state.score {
+			t.Errorf("score %d, expected %d", state.score, iteration.expectedScore)
+		}
+
+		if iteration.expectedGameState != state.state {
+			t.Errorf("gamestate %d, expected %d", state.state, iteration.expectedGameState)
+		}
+	}
+}
+
+// TestSeededSessions makes sure that sessions are reproducible via their
+// seed, as this is required for the daily challenge and replays.
+func TestSeededSessions(t *testing.T) {
+	describe := func(session *Session) string {
+		var description []rune
+		for _, cell := range session.gameBoard {
+			description = append(description, cell.Character)
+		}
+		for _, index := range session.indicesToHide {
+			description = append(description, rune('A'+index))
+		}
+		return string(description)
+	}
+
+	first := newTestSession(t, BuiltInDifficulties()[4], ClassicMode, 42, NewManualClock(time.Time{}))
+	second := newTestSession(t, BuiltInDifficulties()[4], ClassicMode, 42, NewManualClock(time.Time{}))
+	if describe(first) != describe(second) {
+		t.Errorf("sessions with the same seed differ: %s vs %s", describe(first), describe(second))
+	}
+
+	third := newTestSession(t, BuiltInDifficulties()[4], ClassicMode, 43, NewManualClock(time.Time{}))
+	if describe(first) == describe(third) {
+		t.Errorf("sessions with different seeds are equal: %s", describe(first))
+	}
+
+}
+
+// TestHideOrder makes sure that the cells remember the order in which they
+// were hidden, which is the reverse of the hide stack.
+func TestHideOrder(t *testing.T) {
+	session := newTestSession(t, BuiltInDifficulties()[4], ClassicMode, 42, NewManualClock(time.Time{}))
+	hideStack := append([]int(nil), session.indicesToHide...)
+	for i := 0; i < 3; i++ {
+		session.Tick()
+	}
+
+	snapshot := session.Snapshot()
+	for order := 1; order <= len(hideStack); order++ {
+		expected := order
+		if order > 3 {
+			expected = 0
+		}
+		if cell := snapshot.Board[hideStack[len(hideStack)-order]]; cell.HideOrder != expected {
+			t.Errorf("cell %c has hide order %d, expected %d", cell.Character, cell.HideOrder, expected)
+		}
+	}
+}
+
+// TestRuneHidingTiming tests the coroutine that hides runes. Using a manual
+// clock, we can verify that every rune is hidden at the exact moment and
+// that the game ends as soon as too many runes are hidden.
+func TestRuneHidingTiming(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "easy",
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		RowCount:                3,
+		ColumnCount:             2,
+		StartDelay:              750 * time.Millisecond,
+		HideTimes:               1250 * time.Millisecond,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '6'),
+		},
+	}
+
+	testClock := NewManualClock(time.Time{})
+	session := newTestSession(t, testDifficulty, ClassicMode, 1, testClock)
+	updates := session.Subscribe()
+	session.Start(context.Background())
+
+	countHidden := func() (int, State) {
+		session.mutex.Lock()
+		defer session.mutex.Unlock()
+
+		var hiddenCount int
+		for _, cell := range session.gameBoard {
+			if cell.State == Hidden {
+				hiddenCount++
+			}
+		}
+		return hiddenCount, session.state
+	}
+
+	//A third of the board being hidden is fine, but the third hidden cell
+	//crosses the 40 percent threshold.
+	expectedStates := []State{Ongoing, Ongoing, GameOver}
+	elapsed := time.Duration(0)
+	for index, expectedState := range expectedStates {
+		hideAt := testDifficulty.StartDelay + time.Duration(index+1)*testDifficulty.HideTimes
+
+		waitForTimers(t, testClock, 1)
+		testClock.Advance(hideAt - elapsed - time.Nanosecond)
+		if hiddenCount, _ := countHidden(); hiddenCount != index {
+			t.Fatalf("%d cells hidden before %s, expected %d", hiddenCount, hideAt, index)
+		}
+
+		testClock.Advance(time.Nanosecond)
+		elapsed = hideAt
+		select {
+		case <-updates:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("cell wasn't hidden at %s", hideAt)
+		}
+
+		hiddenCount, state := countHidden()
+		if hiddenCount != index+1 {
+			t.Errorf("%d cells hidden at %s, expected %d", hiddenCount, hideAt, index+1)
+		}
+		if state != expectedState {
+			t.Errorf("state at %s is %s, expected %s", hideAt, state, expectedState)
+		}
+	}
+
+	session.mutex.Lock()
+	if duration := session.duration(); duration != elapsed {
+		t.Errorf("game took %s, expected %s", duration, elapsed)
+	}
+	session.mutex.Unlock()
+
+	//No more cells may be hidden after the game has ended.
+	waitForTimers(t, testClock, 1)
+	testClock.Advance(10 * testDifficulty.HideTimes)
+	time.Sleep(10 * time.Millisecond)
+	if hiddenCount, _ := countHidden(); hiddenCount != len(expectedStates) {
+		t.Errorf("%d cells hidden after the game has ended, expected %d", hiddenCount, len(expectedStates))
+	}
+}
+
+func TestEndlessMode(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "easy",
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		RowCount:                3,
+		ColumnCount:             1,
+		StartDelay:              time.Second,
+		HideTimes:               time.Second,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '6'),
+		},
+	}
+
+	session := newTestSession(t, testDifficulty, EndlessMode, 1, NewManualClock(time.Time{}))
+	guessAll := func() {
+		for round := session.round; session.round == round; {
+			session.hideRune()
+			for _, cell := range session.gameBoard {
+				if cell.State == Hidden {
+					session.inputRunePress(cell.Character)
+				}
+			}
+		}
+	}
+
+	//The second round grows the board, the third one can't grow anymore due
+	//to the small pool, so the hide time is reduced instead.
+	guessAll()
+	if session.state != Ongoing || session.round != 2 || len(session.gameBoard) != 6 {
+		t.Fatalf("expected second round with 6 cells, got %s in round %d with %d cells",
+			session.state, session.round, len(session.gameBoard))
+	}
+	session.inputRunePress('-')
+	if session.score != 13 {
+		t.Errorf("score %d, expected 13", session.score)
+	}
+
+	guessAll()
+	if session.round != 3 || len(session.gameBoard) != 6 || session.difficulty.HideTimes != 900*time.Millisecond {
+		t.Errorf("unexpected third round: %d cells, hide time %s", len(session.gameBoard), session.difficulty.HideTimes)
+	}
+	if session.score != 43 {
+		t.Errorf("score %d, expected 43", session.score)
+	}
+
+	//Losing ends the whole run.
+	session.hideRune()
+	session.hideRune()
+	session.hideRune()
+	if snapshot := session.Snapshot(); snapshot.State != GameOver || snapshot.RoundsSurvived() != 2 {
+		t.Errorf("expected game over after 2 rounds, got %s after %d", snapshot.State, snapshot.RoundsSurvived())
+	}
+}
+
+// TestPauseKeepsRemainingTime makes sure that pausing stops the hiding of
+// runes and that the remaining time until the next hide is kept.
+func TestPauseKeepsRemainingTime(t *testing.T) {
+	testDifficulty := &Difficulty{
+		VisibleName:             "normal",
+		CorrectGuessPoints:      5,
+		InvalidKeyPressPenality: 2,
+		RowCount:                3,
+		ColumnCount:             3,
+		StartDelay:              time.Second,
+		HideTimes:               time.Second,
+		MaxHiddenRatio:          DefaultMaxHiddenRatio,
+		MinimumWinningScore:     DefaultMinimumWinningScore,
+		RunePools: [][]rune{
+			RuneRange('1', '9'),
+		},
+	}
+
+	testClock := NewManualClock(time.Time{})
+	session := newTestSession(t, testDifficulty, ClassicMode, 1, testClock)
+	updates := session.Subscribe()
+	session.Start(context.Background())
+
+	hiddenCount := func() int {
+		session.mutex.Lock()
+		defer session.mutex.Unlock()
+		_, hiddenCellCount, _ := session.countCells()
+		return hiddenCellCount
+	}
+
+	//The first hide would happen after 2 seconds, we pause after 1.5.
+	waitForTimers(t, testClock, 1)
+	testClock.Advance(1500 * time.Millisecond)
+	session.Pause(PausedByPlayer)
+
+	testClock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if count := hiddenCount(); count != 0 {
+		t.Fatalf("%d cells hidden while paused", count)
+	}
+
+	session.Resume(PausedByPlayer)
+
+	waitForTimers(t, testClock, 1)
+	testClock.Advance(500*time.Millisecond - time.Nanosecond)
+	if count := hiddenCount(); count != 0 {
+		t.Fatalf("%d cells hidden before the remaining time has passed", count)
+	}
+
+	//Pausing and resuming notify as well, so we get rid of those first.
+	select {
+	case <-updates:
+	default:
+	}
+	testClock.Advance(time.Nanosecond)
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cell wasn't hidden after resuming")
+	}
+	if count := hiddenCount(); count != 1 {
+		t.Errorf("%d cells hidden after resuming, expected 1", count)
+	}
+}
+
+func TestCancelledSessionStopsHiding(t *testing.T) {
+	testClock := NewManualClock(time.Time{})
+	session := newTestSession(t, BuiltInDifficulties()[1], ClassicMode, 1, testClock)
+	ctx, cancel := context.WithCancel(context.Background())
+	session.Start(ctx)
+
+	waitForTimers(t, testClock, 1)
+	cancel()
+	//The coroutine stops its timer before exiting.
+	deadline := time.Now().Add(5 * time.Second)
+	for testClock.PendingTimers() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the timer wasn't stopped after cancelling")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	testClock.Advance(time.Minute)
+	if snapshot := session.Snapshot(); snapshot.HiddenCellCount != 0 || snapshot.State != Ongoing {
+		t.Errorf("cancelled session has changed: %d hidden, %s", snapshot.HiddenCellCount, snapshot.State)
+	}
+}
+
+func TestPausedTimeIsExcludedFromDuration(t *testing.T) {
+	testClock := NewManualClock(time.Time{})
+	session := newTestSession(t, BuiltInDifficulties()[1], ClassicMode, 1, testClock)
+
+	testClock.Advance(10 * time.Second)
+	session.pause(PausedByPlayer)
+	session.pause(PausedByScreenSize)
+	testClock.Advance(time.Minute)
+	if duration := session.duration(); duration != 10*time.Second {
+		t.Errorf("duration while paused is %s, expected 10s", duration)
+	}
+
+	//The session stays paused until all reasons are gone.
+	session.resume(PausedByPlayer)
+	testClock.Advance(time.Minute)
+	if !session.isPaused() {
+		t.Error("session resumed even though the screen is still too small")
+	}
+	session.resume(PausedByScreenSize)
+
+	//Input is ignored while paused.
+	session.pause(PausedByPlayer)
+	session.inputRunePress('-')
+	if session.invalidKeyPresses != 0 {
+		t.Error("input has been accepted while paused")
+	}
+
+	testClock.Advance(5 * time.Second)
+	session.surrender()
+	testClock.Advance(time.Minute)
+	if duration := session.duration(); duration != 10*time.Second {
+		t.Errorf("duration after surrendering while paused is %s, expected 10s", duration)
+	}
+}
+
+// TestUnchangedPauseDoesNotNotify makes sure that subscribers are only
+// notified if pausing or resuming actually changes something, as front
+// ends call these on every frame.
+func TestUnchangedPauseDoesNotNotify(t *testing.T) {
+	session := newTestSession(t, BuiltInDifficulties()[1], ClassicMode, 1, NewManualClock(time.Time{}))
+	updates := session.Subscribe()
+
+	notified := func() bool {
+		select {
+		case <-updates:
+			return true
+		default:
+			return false
+		}
+	}
+
+	session.Resume(PausedByScreenSize)
+	if notified() {
+		t.Error("resuming a session that isn't paused notified the subscribers")
+	}
+
+	session.Pause(PausedByScreenSize)
+	if !notified() {
+		t.Error("pausing didn't notify the subscribers")
+	}
+	session.Pause(PausedByScreenSize)
+	if notified() {
+		t.Error("pausing for the same reason twice notified the subscribers")
+	}
+	session.Resume(PausedByPlayer)
+	if notified() {
+		t.Error("removing a reason the session isn't paused for notified the subscribers")
+	}
+
+	session.Resume(PausedByScreenSize)
+	if !notified() {
+		t.Error("resuming didn't notify the subscribers")
+	}
+}
+
+// waitForTimers blocks until at least the given amount of timers is
+// waiting to be fired. This allows tests to wait for goroutines to go to
+// sleep before advancing the clock.
+func waitForTimers(t *testing.T, clock *ManualClock, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if clock.PendingTimers() >= count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %d pending timers", count)
+}
+
+func TestBuiltInDifficultiesAreValid(t *testing.T) {
+	for _, diff := range BuiltInDifficulties() {
+		if validationError := diff.Validate(); validationError != nil {
+			t.Errorf("difficulty %s is invalid: %s", diff.VisibleName, validationError)
+		}
+	}
+}
+
+func TestInvalidSessionsAreRejected(t *testing.T) {
+	tooFewRunes := *BuiltInDifficulties()[1]
+	tooFewRunes.RunePools = [][]rune{RuneRange('1', '3')}
+	if _, sessionError := NewSession(&tooFewRunes, ClassicMode, 1, NewManualClock(time.Time{})); sessionError == nil {
+		t.Error("a board with too few runes has been accepted")
+	}
+
+	largeBoard := *BuiltInDifficulties()[1]
+	largeBoard.ColumnCount = 20
+	largeBoard.RunePools = [][]rune{RuneRange('a', 'z'), RuneRange('A', 'Z'), RuneRange('0', '9'), RuneRange('!', '/')}
+	if _, sessionError := NewSession(&largeBoard, ClassicMode, 1, NewManualClock(time.Time{})); sessionError != nil {
+		t.Fatalf("the difficulty should be valid in classic mode: %s", sessionError)
+	}
+	if _, sessionError := NewSession(&largeBoard, PairsMode, 1, NewManualClock(time.Time{})); sessionError == nil {
+		t.Error("a board that can't be addressed via coordinates has been accepted in pairs mode")
+	}
+}
+
+// TestConfigurableRules makes sure that the loss and win rules defined by
+// the difficulty are applied and that the end reason matches the rule.
+func TestConfigurableRules(t *testing.T) {
+	newDifficulty := func(maxHiddenRatio float64, maxHiddenCount, minimumWinningScore int) *Difficulty {
+		return &Difficulty{
+			VisibleName:             "rules",
+			HideTimes:               time.Second,
+			CorrectGuessPoints:      5,
+			InvalidKeyPressPenality: 10,
+			RowCount:                3,
+			ColumnCount:             2,
+			MaxHiddenRatio:          maxHiddenRatio,
+			MaxHiddenCount:          maxHiddenCount,
+			MinimumWinningScore:     minimumWinningScore,
+			RunePools: [][]rune{
+				RuneRange('1', '6'),
+			},
+		}
+	}
+	hideAll := func(session *Session) {
+		for i := 0; i < 6; i++ {
+			session.Tick()
+		}
+	}
+	guessAll := func(session *Session) {
+		for _, cell := range session.Snapshot().Board {
+			session.PressRune(cell.Character)
+		}
+	}
+
+	t.Run("absolute limit", func(t *testing.T) {
+		session := newTestSession(t, newDifficulty(0, 1, 1), ClassicMode, 1, NewManualClock(time.Time{}))
+		if limit := session.Snapshot().HiddenCellLimit; limit != 1 {
+			t.Errorf("hidden cell limit is %d, expected 1", limit)
+		}
+		session.Tick()
+		if snapshot := session.Snapshot(); snapshot.State != GameOver || snapshot.EndReason != TooManyHidden {
+			t.Errorf("expected loss due to too many hidden cells, got %s (%d)", snapshot.State, snapshot.EndReason)
+		}
+	})
+
+	t.Run("whichever limit comes first", func(t *testing.T) {
+		session := newTestSession(t, newDifficulty(0.5, 2, 1), ClassicMode, 1, NewManualClock(time.Time{}))
+		if limit := session.Snapshot().HiddenCellLimit; limit != 2 {
+			t.Errorf("hidden cell limit is %d, expected 2", limit)
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		session := newTestSession(t, newDifficulty(0, 0, 1), ClassicMode, 1, NewManualClock(time.Time{}))
+		hideAll(session)
+		if snapshot := session.Snapshot(); snapshot.State != Ongoing || snapshot.HiddenCellLimit != 0 {
+			t.Fatalf("expected ongoing game without limit, got %s with limit %d", snapshot.State, snapshot.HiddenCellLimit)
+		}
+		guessAll(session)
+		if snapshot := session.Snapshot(); snapshot.State != Victory || snapshot.EndReason != AllGuessed {
+			t.Errorf("expected victory, got %s (%d)", snapshot.State, snapshot.EndReason)
+		}
+	})
+
+	t.Run("score too low", func(t *testing.T) {
+		session := newTestSession(t, newDifficulty(0, 0, 30), ClassicMode, 1, NewManualClock(time.Time{}))
+		session.PressRune('-')
+		hideAll(session)
+		guessAll(session)
+		if snapshot := session.Snapshot(); snapshot.State != GameOver || snapshot.EndReason != ScoreTooLow {
+			t.Errorf("expected loss due to a low score, got %s (%d) with %d points",
+				snapshot.State, snapshot.EndReason, snapshot.Score)
+		}
+	})
+
+	t.Run("negative minimum score", func(t *testing.T) {
+		session := newTestSession(t, newDifficulty(0, 0, -100), ClassicMode, 1, NewManualClock(time.Time{}))
+		for i := 0; i < 5; i++ {
+			session.PressRune('-')
+		}
+		hideAll(session)
+		guessAll(session)
+		if snapshot := session.Snapshot(); snapshot.State != Victory || snapshot.Score != -20 {
+			t.Errorf("expected victory with -20 points, got %s with %d points", snapshot.State, snapshot.Score)
+		}
+	})
+}
+
+// newTestSession creates a session, failing the test if the difficulty
+// can't be played in the given mode.
+func newTestSession(t *testing.T, difficulty *Difficulty, mode Mode, seed int64, clock Clock) *Session {
+	t.Helper()
+
+	session, sessionError := NewSession(difficulty, mode, seed, clock)
+	if sessionError != nil {
+		t.Fatal(sessionError)
+	}
+	return session
+}
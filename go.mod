@@ -2,4 +2,7 @@ module github.com/Bios-Marcel/memoryalike
 
 go 1.14
 
-require github.com/gdamore/tcell v1.4.0
+require (
+	github.com/gdamore/tcell/v2 v2.5.4
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
+)
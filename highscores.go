@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+const (
+	highScoresFileName = "highscores.json"
+	//maxHighScoresPerDifficulty limits the leaderboard size, so the file
+	//doesn't grow forever and the table fits on the screen.
+	maxHighScoresPerDifficulty = 10
+)
+
+// highScoreEntry represents the result of a single finished game.
+type highScoreEntry struct {
+	Score             int           `json:"score"`
+	InvalidKeyPresses int           `json:"invalidKeyPresses"`
+	Outcome           string        `json:"outcome"`
+	Duration          time.Duration `json:"duration"`
+	Timestamp         time.Time     `json:"timestamp"`
+	//Player is the name of the player who achieved the result. It's only
+	//known for games played via the SSH server.
+	Player string `json:"player,omitempty"`
+}
+
+// highScoreTable is a persistent leaderboard. The entries are grouped by the
+// visible name of the difficulty they were achieved on and are sorted by
+// score in descending order. The table is safe for concurrent use, as the
+// SSH server shares it between all players.
+type highScoreTable struct {
+	mutex   sync.Mutex
+	path    string
+	entries map[string][]*highScoreEntry
+}
+
+// highScoresPath returns the location of the high score file.
+func highScoresPath() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, highScoresFileName), nil
+}
+
+// loadHighScores reads the high score table stored at the given path. If no
+// file exists yet, an empty table is returned. An empty path results in a
+// table that only lives in memory.
+func loadHighScores(path string) (*highScoreTable, error) {
+	table := &highScoreTable{
+		path:    path,
+		entries: make(map[string][]*highScoreEntry),
+	}
+
+	if path == "" {
+		return table, nil
+	}
+
+	data, readError := ioutil.ReadFile(path)
+	if readError != nil {
+		if os.IsNotExist(readError) {
+			return table, nil
+		}
+		return nil, readError
+	}
+
+	if parseError := json.Unmarshal(data, &table.entries); parseError != nil {
+		return nil, parseError
+	}
+
+	return table, nil
+}
+
+// add inserts the entry into the leaderboard of the given difficulty. If the
+// entry isn't good enough to make it onto the leaderboard, false is
+// returned. The table isn't saved automatically.
+func (table *highScoreTable) add(difficultyName string, entry *highScoreEntry) bool {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+
+	entries := append(table.entries[difficultyName], entry)
+	//Stable, so that older entries win ties.
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].Score > entries[b].Score
+	})
+
+	if len(entries) > maxHighScoresPerDifficulty {
+		entries = entries[:maxHighScoresPerDifficulty]
+	}
+	table.entries[difficultyName] = entries
+
+	for _, kept := range entries {
+		if kept == entry {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get returns a copy of the leaderboard for the given difficulty.
+func (table *highScoreTable) get(difficultyName string) []*highScoreEntry {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+
+	//Adding an entry sorts the leaderboard in place, so we mustn't hand
+	//out the original.
+	return append([]*highScoreEntry(nil), table.entries[difficultyName]...)
+}
+
+// save writes the table to disk, creating the config directory if required.
+func (table *highScoreTable) save() error {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+
+	return table.writeToDisk()
+}
+
+func (table *highScoreTable) writeToDisk() error {
+	if table.path == "" {
+		return nil
+	}
+
+	data, marshalError := json.MarshalIndent(table.entries, "", "  ")
+	if marshalError != nil {
+		return marshalError
+	}
+
+	if mkdirError := os.MkdirAll(filepath.Dir(table.path), 0755); mkdirError != nil {
+		return mkdirError
+	}
+
+	return ioutil.WriteFile(table.path, data, 0644)
+}
+
+// newHighScoreEntry creates an entry representing the result of the given
+// session. The session should already be over.
+func newHighScoreEntry(snapshot engine.Snapshot) *highScoreEntry {
+	return &highScoreEntry{
+		Score:             snapshot.Score,
+		InvalidKeyPresses: snapshot.InvalidKeyPresses,
+		Outcome:           snapshot.State.String(),
+		Duration:          snapshot.Duration,
+		Timestamp:         snapshot.EndTime,
+	}
+}
+
+// leaderboardName returns the name under which the results achieved on
+// the given difficulty and mode are stored in the high scores.
+func leaderboardName(difficultyName string, mode engine.Mode) string {
+	if mode == engine.ClassicMode {
+		return difficultyName
+	}
+
+	return fmt.Sprintf("%s (%s)", difficultyName, mode)
+}
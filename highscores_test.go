@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHighScoreTable(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "nested", highScoresFileName)
+	table, loadError := loadHighScores(path)
+	if loadError != nil {
+		t.Fatal(loadError)
+	}
+
+	for score := 1; score <= maxHighScoresPerDifficulty; score++ {
+		if !table.add("normal", &highScoreEntry{Score: score * 10}) {
+			t.Errorf("entry with score %d should have been added", score*10)
+		}
+	}
+
+	if table.add("normal", &highScoreEntry{Score: 5}) {
+		t.Error("entry with the lowest score shouldn't make it into a full table")
+	}
+
+	tie := &highScoreEntry{Score: 50, Timestamp: time.Now()}
+	if !table.add("normal", tie) {
+		t.Error("entry with a tied score should have been added")
+	}
+
+	entries := table.get("normal")
+	if len(entries) != maxHighScoresPerDifficulty {
+		t.Fatalf("expected %d entries, got %d", maxHighScoresPerDifficulty, len(entries))
+	}
+	if entries[0].Score != 100 || entries[6] != tie || entries[len(entries)-1].Score != 20 {
+		t.Errorf("entries aren't sorted as expected: %+v", entries)
+	}
+
+	if saveError := table.save(); saveError != nil {
+		t.Fatal(saveError)
+	}
+
+	reloaded, reloadError := loadHighScores(path)
+	if reloadError != nil {
+		t.Fatal(reloadError)
+	}
+	if len(reloaded.get("normal")) != maxHighScoresPerDifficulty || len(reloaded.get("hard")) != 0 {
+		t.Errorf("reloaded table differs: %+v", reloaded.entries)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	keymapFileName = "keymap.json"
+	spaceKeyName   = "Space"
+)
+
+// action is something the player can trigger via a key binding.
+type action string
+
+const (
+	upAction    action = "up"
+	downAction  action = "down"
+	leftAction  action = "left"
+	rightAction action = "right"
+	//selectAction starts the selected menu entry.
+	selectAction action = "select"
+	//surrenderAction gives up the current game or goes back to the
+	//previous screen.
+	surrenderAction action = "surrender"
+	restartAction   action = "restart"
+	quitAction      action = "quit"
+	pauseAction     action = "pause"
+)
+
+// keyContexts groups the actions that are available at the same time. Two
+// actions of the same context mustn't share a key, as we couldn't tell
+// which one the player meant.
+var keyContexts = map[string][]action{
+	"menu":        {upAction, downAction, leftAction, rightAction, selectAction, quitAction},
+	"high scores": {leftAction, rightAction, selectAction, surrenderAction, quitAction},
+	"game":        {surrenderAction, restartAction, quitAction, pauseAction},
+}
+
+// gameActions are the actions available while a game is running. Their keys
+// can't be typed on the board.
+var gameActions = keyContexts["game"]
+
+// keyBinding is either a special key, such as tcell.KeyEnter, or a
+// character, in which case key is tcell.KeyRune.
+type keyBinding struct {
+	key tcell.Key
+	r   rune
+}
+
+// keymap maps each action to the keys that trigger it.
+type keymap map[action][]keyBinding
+
+// defaultKeymap returns the bindings used if the player hasn't defined any.
+func defaultKeymap() keymap {
+	return keymap{
+		upAction:        {{key: tcell.KeyUp}, {key: tcell.KeyRune, r: 'w'}, {key: tcell.KeyRune, r: 'k'}},
+		downAction:      {{key: tcell.KeyDown}, {key: tcell.KeyRune, r: 's'}, {key: tcell.KeyRune, r: 'j'}},
+		leftAction:      {{key: tcell.KeyLeft}, {key: tcell.KeyRune, r: 'a'}, {key: tcell.KeyRune, r: 'h'}},
+		rightAction:     {{key: tcell.KeyRight}, {key: tcell.KeyRune, r: 'd'}, {key: tcell.KeyRune, r: 'l'}},
+		selectAction:    {{key: tcell.KeyEnter}},
+		surrenderAction: {{key: tcell.KeyEscape}},
+		restartAction:   {{key: tcell.KeyCtrlR}},
+		quitAction:      {{key: tcell.KeyCtrlC}},
+		pauseAction:     {{key: tcell.KeyCtrlP}},
+	}
+}
+
+// keymapPath returns the location of the file containing the player's key
+// bindings.
+func keymapPath() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, keymapFileName), nil
+}
+
+// loadKeymap reads the key bindings at the given path. The file maps action
+// names to lists of keys, e.g. {"down": ["Down", "j"]}. Actions that aren't
+// part of the file keep their default bindings. A non-existent file isn't
+// an error, as the defaults are used in that case. All problems are
+// reported at once.
+func loadKeymap(path string) (keymap, error) {
+	keys := defaultKeymap()
+
+	data, readError := ioutil.ReadFile(path)
+	if readError != nil {
+		if os.IsNotExist(readError) {
+			return keys, nil
+		}
+		return nil, readError
+	}
+
+	var definitions map[string][]string
+	if parseError := json.Unmarshal(data, &definitions); parseError != nil {
+		return nil, fmt.Errorf("error parsing keymap file '%s': %w", path, parseError)
+	}
+
+	var problems []string
+	for name, keyNames := range definitions {
+		if _, known := keys[action(name)]; !known {
+			problems = append(problems, fmt.Sprintf("\tunknown action %q", name))
+			continue
+		}
+		if len(keyNames) == 0 {
+			problems = append(problems, fmt.Sprintf("\taction %q needs at least one key", name))
+			continue
+		}
+
+		bindings := make([]keyBinding, 0, len(keyNames))
+		for _, keyName := range keyNames {
+			binding, bindingError := parseKeyBinding(keyName)
+			if bindingError != nil {
+				problems = append(problems, fmt.Sprintf("\taction %q: %s", name, bindingError))
+				continue
+			}
+			bindings = append(bindings, binding)
+		}
+		keys[action(name)] = bindings
+	}
+	//Conflicts would be misleading if some bindings couldn't be parsed.
+	if len(problems) == 0 {
+		problems = keys.findConflicts()
+	}
+
+	if len(problems) > 0 {
+		//Map iteration isn't ordered, but the output should be stable.
+		sort.Strings(problems)
+		return nil, fmt.Errorf("invalid keymap in '%s':\n%s", path, strings.Join(problems, "\n"))
+	}
+
+	return keys, nil
+}
+
+// parseKeyBinding turns a key name into a binding. A single character is
+// bound as is, anything else has to be "Space" or one of the names defined
+// by tcell, such as "Enter" or "Ctrl-R". The names are case insensitive.
+func parseKeyBinding(name string) (keyBinding, error) {
+	if runes := []rune(name); len(runes) == 1 {
+		return keyBinding{key: tcell.KeyRune, r: runes[0]}, nil
+	}
+
+	//tcell treats the space bar as a regular character, but a blank
+	//would be hard to read in the file and in the hints.
+	if strings.EqualFold(name, spaceKeyName) {
+		return keyBinding{key: tcell.KeyRune, r: ' '}, nil
+	}
+
+	for key, keyName := range tcell.KeyNames {
+		if strings.EqualFold(keyName, name) {
+			return keyBinding{key: key}, nil
+		}
+	}
+
+	return keyBinding{}, fmt.Errorf("unknown key %q", name)
+}
+
+// String returns the name of the key, as it's used in the keymap file.
+func (binding keyBinding) String() string {
+	if binding.key == tcell.KeyRune && binding.r == ' ' {
+		return spaceKeyName
+	}
+	if binding.key == tcell.KeyRune {
+		return string(binding.r)
+	}
+
+	return tcell.KeyNames[binding.key]
+}
+
+// matches decides whether the event has been caused by the bound key.
+func (binding keyBinding) matches(event *tcell.EventKey) bool {
+	if binding.key == tcell.KeyRune {
+		return event.Key() == tcell.KeyRune && event.Rune() == binding.r
+	}
+
+	return event.Key() == binding.key
+}
+
+// findConflicts returns a description of each key that is bound to more
+// than one action of the same context.
+func (keys keymap) findConflicts() []string {
+	var problems []string
+	for context, contextActions := range keyContexts {
+		boundTo := make(map[keyBinding]action)
+		for _, contextAction := range contextActions {
+			for _, binding := range keys[contextAction] {
+				if other, bound := boundTo[binding]; bound && other != contextAction {
+					problems = append(problems, fmt.Sprintf("\tkey %q is bound to both %q and %q in the %s",
+						binding, other, contextAction, context))
+				}
+				boundTo[binding] = contextAction
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkDifficulty makes sure that none of the keys used during a game are
+// needed for playing the given difficulty and mode. Otherwise the player
+// wouldn't be able to type that character on the board.
+func (keys keymap) checkDifficulty(diff *engine.Difficulty, mode engine.Mode) error {
+	for _, gameAction := range gameActions {
+		for _, binding := range keys[gameAction] {
+			if binding.key == tcell.KeyRune && diff.IsInputRune(mode, binding.r) {
+				return fmt.Errorf("the key '%s' for %s can't be used, as it has to be typed when playing %s",
+					binding, gameAction, diff.VisibleName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// is decides whether the event triggers the given action.
+func (keys keymap) is(event *tcell.EventKey, wanted action) bool {
+	for _, binding := range keys[wanted] {
+		if binding.matches(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// name returns the name of the first key bound to the action, which is
+// used for telling the player what to press.
+func (keys keymap) name(wanted action) string {
+	if bindings := keys[wanted]; len(bindings) > 0 {
+		return bindings[0].String()
+	}
+
+	return string(wanted)
+}
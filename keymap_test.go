@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestLoadKeymap(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	writeKeymap := func(t *testing.T, content string) string {
+		path := filepath.Join(tempDir, keymapFileName)
+		if writeError := ioutil.WriteFile(path, []byte(content), 0600); writeError != nil {
+			t.Fatal(writeError)
+		}
+		return path
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		keys, loadError := loadKeymap(filepath.Join(tempDir, "nope.json"))
+		if loadError != nil {
+			t.Fatal(loadError)
+		}
+
+		//j and k behave like in vim.
+		for _, expected := range []struct {
+			event  *tcell.EventKey
+			action action
+		}{
+			{tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone), downAction},
+			{tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone), upAction},
+			{tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone), downAction},
+			{tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), surrenderAction},
+			{tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModNone), restartAction},
+		} {
+			if !keys.is(expected.event, expected.action) {
+				t.Errorf("expected %s to trigger %s", expected.event.Name(), expected.action)
+			}
+		}
+	})
+
+	t.Run("valid keymap", func(t *testing.T) {
+		path := writeKeymap(t, `{"pause": ["space", "Ctrl-P"], "surrender": ["q"], "up": ["UP"]}`)
+		keys, loadError := loadKeymap(path)
+		if loadError != nil {
+			t.Fatal(loadError)
+		}
+
+		if !keys.is(tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone), surrenderAction) ||
+			keys.is(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), surrenderAction) {
+			t.Errorf("expected only q to surrender, got %v", keys[surrenderAction])
+		}
+		if keys.is(tcell.NewEventKey(tcell.KeyRune, 'w', tcell.ModNone), upAction) {
+			t.Errorf("expected w not to be bound anymore, got %v", keys[upAction])
+		}
+		if name := keys.name(pauseAction); name != "Space" {
+			t.Errorf("expected the pause key to be called space, got %s", name)
+		}
+		//Actions that aren't part of the file keep their defaults.
+		if name := keys.name(restartAction); name != "Ctrl-R" {
+			t.Errorf("expected the restart key to be Ctrl-R, got %s", name)
+		}
+	})
+
+	t.Run("invalid keymap", func(t *testing.T) {
+		path := writeKeymap(t, `{"jump": ["x"], "quit": [], "pause": ["Hyper-X"]}`)
+		keys, loadError := loadKeymap(path)
+		if loadError == nil {
+			t.Fatalf("expected an error, got %v", keys)
+		}
+
+		for _, expected := range []string{
+			`unknown action "jump"`,
+			`action "quit" needs at least one key`,
+			`action "pause": unknown key "Hyper-X"`,
+		} {
+			if !strings.Contains(loadError.Error(), expected) {
+				t.Errorf("error '%s' doesn't mention '%s'", loadError, expected)
+			}
+		}
+	})
+
+	t.Run("conflicting keys", func(t *testing.T) {
+		path := writeKeymap(t, `{"restart": ["Esc"], "select": ["Down"]}`)
+		keys, loadError := loadKeymap(path)
+		if loadError == nil {
+			t.Fatalf("expected an error, got %v", keys)
+		}
+
+		for _, expected := range []string{
+			`key "Esc" is bound to both "surrender" and "restart" in the game`,
+			`key "Down" is bound to both "down" and "select" in the menu`,
+		} {
+			if !strings.Contains(loadError.Error(), expected) {
+				t.Errorf("error '%s' doesn't mention '%s'", loadError, expected)
+			}
+		}
+	})
+}
+
+func TestKeymapDifficultyConflicts(t *testing.T) {
+	keys := defaultKeymap()
+	keys[pauseAction] = []keyBinding{{key: tcell.KeyRune, r: 'p'}}
+
+	for _, testCase := range []struct {
+		difficulty string
+		mode       engine.Mode
+		conflict   bool
+	}{
+		//Easy only consists of digits.
+		{"easy", engine.ClassicMode, false},
+		{"hard", engine.ClassicMode, true},
+		//The board of easy has too few columns for p to be a coordinate.
+		{"easy", engine.PairsMode, false},
+	} {
+		conflictError := keys.checkDifficulty(findDifficulty(testCase.difficulty), testCase.mode)
+		if (conflictError != nil) != testCase.conflict {
+			t.Errorf("unexpected result for %s in %s mode: %v", testCase.difficulty, testCase.mode, conflictError)
+		}
+	}
+
+	keys[pauseAction] = []keyBinding{{key: tcell.KeyRune, r: '2'}}
+	if conflictError := keys.checkDifficulty(findDifficulty("easy"), engine.PairsMode); conflictError == nil {
+		t.Error("expected 2 to conflict with the row labels of pairs mode")
+	}
+}
@@ -1,13 +1,129 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"os"
+	"time"
 
-	"github.com/gdamore/tcell"
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
 )
 
 func main() {
-	screen, screenCreationError := createScreen()
+	seedFlag := flag.Int64("seed", 0, "seed used for generating all boards; random by default")
+	mouseFlag := flag.Bool("mouse", false, "allows selecting menu entries and cells with the mouse")
+	themeFlag := flag.String("theme", themes[0].name, "theme used for drawing the board")
+	hideOrderFlag := flag.Bool("hide-order", false, "shows the order in which the cells were hidden once a game is over")
+	flag.Parse()
+	var fixedSeed *int64
+	flag.Visit(func(setFlag *flag.Flag) {
+		if setFlag.Name == "seed" {
+			fixedSeed = seedFlag
+		}
+	})
+
+	//The keymap is loaded first, as the replay viewer needs it as well.
+	keysPath, _ := keymapPath()
+	keys, keymapError := loadKeymap(keysPath)
+	if keymapError != nil {
+		fmt.Fprintln(os.Stderr, keymapError)
+		os.Exit(1)
+	}
+
+	chosenTheme, themeError := findTheme(*themeFlag)
+	if themeError != nil {
+		fmt.Fprintln(os.Stderr, themeError)
+		os.Exit(1)
+	}
+
+	//renderer used for drawing the board and the menu.
+	renderer := newRenderer(keys, chosenTheme)
+	renderer.showHideOrder = *hideOrderFlag
+
+	if flag.Arg(0) == "stats" {
+		if statsError := runStats(flag.Args()[1:], os.Stdout); statsError != nil {
+			fmt.Fprintln(os.Stderr, statsError)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "replay" {
+		if replayError := runReplay(flag.Args()[1:], renderer); replayError != nil {
+			fmt.Fprintln(os.Stderr, replayError)
+			os.Exit(1)
+		}
+		return
+	}
+
+	//Custom difficulties have to be loaded before the screen is created, as
+	//we wouldn't be able to show any errors to the user otherwise.
+	if customDifficultiesPath, pathError := customDifficultiesPath(); pathError == nil {
+		customDifficulties, loadError := loadCustomDifficulties(customDifficultiesPath, difficulties)
+		if loadError != nil {
+			fmt.Fprintln(os.Stderr, loadError)
+			os.Exit(1)
+		}
+		difficulties = append(difficulties, customDifficulties...)
+	}
+
+	//The bench command has to know the custom difficulties, as it's meant
+	//for checking them before sharing them.
+	if flag.Arg(0) == "bench" {
+		if benchError := runBench(flag.Args()[1:], os.Stdout); benchError != nil {
+			fmt.Fprintln(os.Stderr, benchError)
+			os.Exit(1)
+		}
+		return
+	}
+
+	//Custom difficulties can be hosted as well. The joining player gets
+	//the difficulty from the host.
+	if flag.Arg(0) == "host" || flag.Arg(0) == "join" {
+		run := runHost
+		if flag.Arg(0) == "join" {
+			run = runJoin
+		}
+		if duelError := run(flag.Args()[1:], renderer); duelError != nil {
+			fmt.Fprintln(os.Stderr, duelError)
+			os.Exit(1)
+		}
+		return
+	}
+
+	//Players connected via SSH can play custom difficulties as well.
+	if flag.Arg(0) == "serve" {
+		if serveError := runServe(flag.Args()[1:], renderer, *mouseFlag); serveError != nil {
+			fmt.Fprintln(os.Stderr, serveError)
+			os.Exit(1)
+		}
+		return
+	}
+
+	scoresPath, _ := highScoresPath()
+	scores, scoresError := loadHighScores(scoresPath)
+	if scoresError != nil {
+		fmt.Fprintf(os.Stderr, "error loading high scores from '%s': %s\n", scoresPath, scoresError)
+		os.Exit(1)
+	}
+
+	statsPath, _ := statsPath()
+	stats, statsError := loadStats(statsPath)
+	if statsError != nil {
+		fmt.Fprintf(os.Stderr, "error loading statistics from '%s': %s\n", statsPath, statsError)
+		os.Exit(1)
+	}
+
+	adaptivePath, _ := adaptivePath()
+	adaptive, adaptiveError := loadAdaptiveTrainer(adaptivePath)
+	if adaptiveError != nil {
+		fmt.Fprintf(os.Stderr, "error loading adaptive difficulty from '%s': %s\n", adaptivePath, adaptiveError)
+		os.Exit(1)
+	}
+
+	screen, screenCreationError := createScreen(*mouseFlag)
 	if screenCreationError != nil {
 		panic(screenCreationError)
 	}
@@ -15,128 +131,434 @@ func main() {
 	//Cleans up the terminal buffer and returns it to the shell.
 	defer screen.Fini()
 
-	//renderer used for drawing the board and the menu.
-	renderer := newRenderer()
-	//menuState is reused throughout the runtime of the app. This allows
-	//us to remember the selection inbetween sessions.
-	menuState := newMenuState()
+	loop := &gameLoop{
+		screen:   screen,
+		events:   pollEvents(screen),
+		renderer: renderer,
+		keys:     keys,
+		//menuState is reused throughout the runtime of the app. This
+		//allows us to remember the selection inbetween sessions.
+		menuState: newMenuState(chosenTheme, adaptive),
+		end:       &endScreen{scores: scores, stats: stats, adaptive: adaptive, saveReplays: true},
+		fixedSeed: fixedSeed,
+	}
+	loop.run()
+}
 
-	//blocks till it's closed.
-	openMenu(menuState, screen, renderer)
+// gameLoop owns the session that is currently being played. Input, resize
+// events and redraws are all handled on the goroutine calling run, so the
+// session can be replaced without any locking. The only other goroutines
+// are the one polling the screen for events and the rune hiding coroutine
+// of the current session.
+type gameLoop struct {
+	screen tcell.Screen
+	//events delivers all events of the screen, see pollEvents.
+	events    <-chan tcell.Event
+	renderer  *renderer
+	keys      keymap
+	menuState *menuState
+	end       *endScreen
+	//fixedSeed is the seed passed by the user, if any.
+	fixedSeed *int64
 
-	renderNotificationChannel := make(chan bool)
-	gameSession := newGameSession(renderNotificationChannel, menuState.getDiffculty())
-	gameSession.startRuneHidingCoroutine()
+	session *engine.Session
+	//sessionContext is the context the rune hiding coroutine of the
+	//current session runs in. cancelSession stops the coroutine.
+	sessionContext context.Context
+	cancelSession  context.CancelFunc
+	//updates notifies about changes of the current session. It's nil once
+	//the session has ended, as it won't change anymore.
+	updates <-chan struct{}
+	//recordedSession is the last session that has been added to the high
+	//scores. This makes sure we record each finished session only once.
+	recordedSession *engine.Session
+	//boardFits is the result of the last screen size check. The session
+	//is only paused or resumed when it changes, as both notify the loop.
+	boardFits bool
+	//lastButtons is used for only reacting to the press of a mouse button,
+	//not to holding or releasing it.
+	lastButtons tcell.ButtonMask
+}
 
-	//Listen for key input on the gameboard.
+// pollEvents forwards all events of the screen to the returned channel, so
+// that they can be awaited alongside other events. The channel is closed
+// once the screen has been finalized.
+func pollEvents(screen tcell.Screen) <-chan tcell.Event {
+	events := make(chan tcell.Event)
 	go func() {
+		defer close(events)
 		for {
-			switch event := screen.PollEvent().(type) {
-			case *tcell.EventKey:
-				if event.Key() == tcell.KeyCtrlC {
-					screen.Fini()
-					os.Exit(0)
-				} else if event.Key() == tcell.KeyEscape {
-					//SURRENDER!
-					oldGameSession := gameSession
-					oldGameSession.mutex.Lock()
-
-					//When hitting ESC twice, e.g. when already in the
-					//end-screen, we want to go to the menu instead.
-					if oldGameSession.state != ongoing {
-						openMenu(menuState, screen, renderer)
-						//We have to reset the state, as it's still in the
-						//"game over" state.
-						gameSession = newGameSession(renderNotificationChannel,
-							menuState.getDiffculty())
-						gameSession.startRuneHidingCoroutine()
-					} else {
-						oldGameSession.state = gameOver
-					}
-					oldGameSession.mutex.Unlock()
-					renderNotificationChannel <- true
-				} else if event.Key() == tcell.KeyCtrlR {
-					//RESTART!
-					//Remove previous game over message and such and create
-					//a fresh state, as we needn't save any information for
-					//the next session.
-					oldGameSession := gameSession
-					oldGameSession.mutex.Lock()
-
-					//Make sure the state knows it's supposed to be dead.
-					oldGameSession.state = gameOver
-					screen.Clear()
-					gameSession = newGameSession(renderNotificationChannel,
-						menuState.getDiffculty())
-					gameSession.startRuneHidingCoroutine()
-					gameSession.mutex.Lock()
-
-					oldGameSession.mutex.Unlock()
-					gameSession.mutex.Unlock()
-					renderNotificationChannel <- true
-
-				} else if event.Key() == tcell.KeyRune {
-					gameSession.mutex.Lock()
-					gameSession.inputRunePress(event.Rune())
-					gameSession.mutex.Unlock()
-				}
-			case *tcell.EventResize:
-				gameSession.mutex.Lock()
-				screen.Clear()
-				gameSession.mutex.Unlock()
-				renderNotificationChannel <- true
-				//TODO Handle resize; Validate session;
-			default:
-				//Unsupported or irrelevant event
+			event := screen.PollEvent()
+			if event == nil {
+				return
 			}
+			events <- event
 		}
 	}()
+	return events
+}
 
-	//Gameloop; We draw whenever there's a frame-change. This means we
-	//don't have any specific frame-rates and it could technically happen
-	//that we don't draw for a while. The first frame is drawn without
-	//waiting for a change, so that the screen doesn't stay empty.
+// run shows the menu and then plays sessions until the player quits. This
+// method blocks until then.
+func (loop *gameLoop) run() {
+	if quit := loop.chooseSession(); quit {
+		return
+	}
+	//The session is replaced by restarts, so we have to look it up when
+	//returning. Nobody gets to see it anymore, therefore it's ended.
+	defer func() {
+		loop.cancelSession()
+		loop.session.Abandon()
+	}()
 
+	//We draw whenever there's a frame-change. This means we don't have any
+	//specific frame-rates. However, the status line shows the elapsed
+	//time, therefore we also redraw once per second. The first frame is
+	//drawn without waiting for a change, so that the screen doesn't stay
+	//empty.
+	statusLineTicker := time.NewTicker(time.Second)
+	defer statusLineTicker.Stop()
 	for {
-		//We start lock before draw in order to avoid drawing crap.
-		gameSession.mutex.Lock()
-		renderer.drawGameBoard(screen, gameSession)
-		gameSession.mutex.Unlock()
+		loop.recordResults()
+		loop.draw()
 
-		<-renderNotificationChannel
+		select {
+		case event, open := <-loop.events:
+			if !open {
+				return
+			}
+			if quit := loop.handleEvent(event); quit {
+				return
+			}
+		case _, open := <-loop.updates:
+			if !open {
+				loop.updates = nil
+			}
+			//A new endless round can grow the board.
+			loop.checkScreenSize()
+		case <-statusLineTicker.C:
+		}
 	}
 }
 
-// openMenu draws the game menu and listens for keyboard input.
-// This method blocks until a difficulty has been selected.
-func openMenu(menuState *menuState, targetScreen tcell.Screen, renderer *renderer) {
-MENU_KEY_LOOP:
+// chooseSession opens the menu until a session has been started for the
+// chosen entry. If the player wants to quit, true is returned.
+func (loop *gameLoop) chooseSession() bool {
+	for {
+		if quit := loop.openMenu(); quit {
+			return true
+		}
+		startError := loop.startSession()
+		if startError == nil {
+			return false
+		}
+		//The menu tells the player why the entry can't be played.
+		loop.menuState.message = startError.Error()
+	}
+}
+
+// startSession replaces the current session with a new one for the menu
+// entry that was chosen last and starts hiding runes. The previous session
+// stops hiding runes right away. If the entry can't be played, the error
+// is returned and the current session is kept.
+func (loop *gameLoop) startSession() error {
+	diff, mode := loop.menuState.getDiffculty(), loop.menuState.getMode()
+	//This is checked for each session instead of once when choosing the
+	//entry, as the adaptive difficulty can switch to other characters.
+	if conflictError := loop.keys.checkDifficulty(diff, mode); conflictError != nil {
+		return conflictError
+	}
+
+	session, sessionError := engine.NewSession(diff, mode,
+		chooseSeed(loop.menuState.getSelectedEntry(), loop.fixedSeed), engine.WallClock{})
+	if sessionError != nil {
+		return sessionError
+	}
+
+	if loop.cancelSession != nil {
+		loop.cancelSession()
+	}
+	loop.session = session
+	loop.updates = loop.session.Subscribe()
+	loop.sessionContext, loop.cancelSession = context.WithCancel(context.Background())
+	loop.session.Start(loop.sessionContext)
+
+	//New sessions aren't paused.
+	loop.boardFits = true
+	loop.checkScreenSize()
+	return nil
+}
+
+// checkScreenSize pauses the session if its board doesn't fit on the
+// screen, as it can't be played then, and resumes it once it fits again.
+// It has to be called whenever the screen or the board might have changed
+// their size.
+func (loop *gameLoop) checkScreenSize() {
+	snapshot := loop.session.Snapshot()
+	fits := loop.renderer.fitsOnScreen(loop.screen, snapshot.Difficulty, snapshot.Mode, false)
+	if fits == loop.boardFits {
+		return
+	}
+
+	loop.boardFits = fits
+	if fits {
+		loop.session.Resume(engine.PausedByScreenSize)
+	} else {
+		loop.session.Pause(engine.PausedByScreenSize)
+	}
+}
+
+// recordResults records the current session once it has ended, so that
+// the end screen shows its results.
+func (loop *gameLoop) recordResults() {
+	snapshot := loop.session.Snapshot()
+	if snapshot.State != engine.Ongoing && loop.recordedSession != loop.session {
+		loop.recordedSession = loop.session
+		recordSessionResults(loop.session, snapshot, loop.end)
+		loop.menuState.refreshAdaptiveEntry()
+	}
+}
+
+// draw redraws the board of the current session.
+func (loop *gameLoop) draw() {
+	loop.renderer.drawGameBoard(loop.screen, loop.session.Snapshot(), loop.end)
+}
+
+// handleEvent reacts to a single event of the screen during a game. If the
+// player wants to quit, true is returned.
+func (loop *gameLoop) handleEvent(screenEvent tcell.Event) bool {
+	switch event := screenEvent.(type) {
+	case *tcell.EventKey:
+		if loop.keys.is(event, quitAction) {
+			return true
+		} else if loop.keys.is(event, surrenderAction) {
+			//SURRENDER!
+			//When hitting ESC twice, e.g. when already in the end-screen,
+			//we want to go to the menu instead.
+			//We have to reset the state afterwards, as it's still in the
+			//"game over" state.
+			if loop.session.Snapshot().State != engine.Ongoing {
+				if quit := loop.chooseSession(); quit {
+					return true
+				}
+			} else {
+				loop.session.Surrender()
+			}
+		} else if loop.keys.is(event, restartAction) {
+			//RESTART!
+			//Remove previous game over message and such and create a fresh
+			//state, as we needn't save any information for the next
+			//session. Make sure the old state knows it's supposed to be
+			//dead.
+			loop.session.Abandon()
+			loop.screen.Clear()
+			//If the entry can't be played anymore, the player has to
+			//choose another one.
+			if startError := loop.startSession(); startError != nil {
+				loop.menuState.message = startError.Error()
+				if quit := loop.chooseSession(); quit {
+					return true
+				}
+			}
+		} else if loop.keys.is(event, pauseAction) {
+			//PAUSE!
+			loop.session.TogglePause(engine.PausedByPlayer)
+		} else if event.Key() == tcell.KeyRune {
+			loop.session.PressRune(event.Rune())
+		}
+	case *tcell.EventMouse:
+		//Only game modes that involve picking positions react to clicking
+		//a cell.
+		pressed := event.Buttons()&tcell.Button1 != 0 && loop.lastButtons&tcell.Button1 == 0
+		loop.lastButtons = event.Buttons()
+		if pressed {
+			mouseX, mouseY := event.Position()
+			if index, hit := loop.renderer.cellAt(loop.screen, loop.session.Snapshot().Difficulty, mouseX, mouseY); hit {
+				loop.session.SelectCell(index)
+			}
+		}
+	case *tcell.EventResize:
+		loop.screen.Clear()
+		loop.checkScreenSize()
+	default:
+		//Unsupported or irrelevant event
+	}
+
+	return false
+}
+
+// recordSessionResults adds the finished session to the high scores and
+// statistics, adjusts the adaptive level and saves its replay if wanted. Hot-seat
+// sessions are left out of the high scores. Errors are shown on the end screen, as
+// we don't want to interrupt the game.
+func recordSessionResults(session *engine.Session, snapshot engine.Snapshot, end *endScreen) {
+	//The score of a hot-seat game is shared by two players, so it can't
+	//be compared to the high scores.
+	if snapshot.Mode != engine.HotSeatMode {
+		entry := newHighScoreEntry(snapshot)
+		entry.Player = end.player
+		end.lastScore = nil
+		if end.scores.add(leaderboardName(snapshot.Difficulty.VisibleName, snapshot.Mode), entry) {
+			end.lastScore = entry
+		}
+		end.scoresSaveError = end.scores.save()
+	}
+	end.stats.add(snapshot)
+	end.statsSaveError = end.stats.save()
+	end.adaptive.add(snapshot)
+	end.adaptiveSaveError = end.adaptive.save()
+
+	end.replayPath = ""
+	end.replaySaveError = nil
+	if !end.saveReplays {
+		return
+	}
+	if replaysDir, replaysDirError := replaysDirectory(); replaysDirError != nil {
+		end.replaySaveError = replaysDirError
+	} else {
+		end.replayPath, end.replaySaveError = saveRecording(session.Recording(), replaysDir)
+	}
+}
+
+// chooseSeed decides which seed the next session started via the given menu
+// entry uses. The daily challenge always uses the seed of the current day.
+// Otherwise the seed passed by the user is used, if there is one.
+func chooseSeed(entry *menuEntry, fixedSeed *int64) int64 {
+	if entry.kind == dailyChallengeEntry {
+		return dailySeed(time.Now())
+	}
+
+	if fixedSeed != nil {
+		return *fixedSeed
+	}
+
+	return time.Now().UnixNano()
+}
+
+// openMenu draws the game menu and listens for keyboard and mouse input.
+// This method blocks until a difficulty has been selected or the player
+// wants to quit, in which case true is returned.
+func (loop *gameLoop) openMenu() bool {
+	menuState, targetScreen, renderer, keys := loop.menuState, loop.screen, loop.renderer, loop.keys
+
+	//activateSelection starts the selected entry. It returns whether the
+	//menu has to be closed afterwards and whether the player wants to
+	//quit.
+	activateSelection := func() (bool, bool) {
+		if menuState.getSelectedEntry().kind == highScoresEntry {
+			return false, loop.openHighScores()
+		}
+		if menuState.getSelectedEntry().kind == statsEntry {
+			return false, loop.openStats()
+		}
+		if menuState.getSelectedEntry().kind == themeEntry {
+			menuState.selectNextTheme()
+			renderer.theme = menuState.theme
+			return false, false
+		}
+
+		//We clear in order to get rid of the menu for sure.
+		targetScreen.Clear()
+		return true, false
+	}
+
 	for {
 		//We draw the menu initially and then once after any event.
 		renderer.drawMenu(targetScreen, menuState)
 
-		switch event := targetScreen.PollEvent().(type) {
+		screenEvent, open := <-loop.events
+		if !open {
+			return true
+		}
+		switch event := screenEvent.(type) {
 		case *tcell.EventKey:
-			if event.Key() == tcell.KeyDown || event.Rune() == 's' || event.Rune() == 'k' {
-				if menuState.selectedDifficulty >= len(difficulties)-1 {
-					menuState.selectedDifficulty = 0
-				} else {
-					menuState.selectedDifficulty++
+			menuState.message = ""
+			if keys.is(event, downAction) {
+				menuState.selectNext()
+			} else if keys.is(event, upAction) {
+				menuState.selectPrevious()
+			} else if keys.is(event, rightAction) {
+				menuState.selectNextMode()
+			} else if keys.is(event, leftAction) {
+				menuState.selectPreviousMode()
+			} else if keys.is(event, selectAction) {
+				if closeMenu, quit := activateSelection(); closeMenu || quit {
+					return quit
 				}
-			} else if event.Key() == tcell.KeyUp || event.Rune() == 'w' || event.Rune() == 'j' {
-				if menuState.selectedDifficulty <= 0 {
-					menuState.selectedDifficulty = len(difficulties) - 1
-				} else {
-					menuState.selectedDifficulty--
+			} else if keys.is(event, quitAction) {
+				return true
+			}
+		case *tcell.EventMouse:
+			//Clicking an entry behaves the same as selecting it and
+			//hitting enter. Holding the button or dragging the mouse
+			//mustn't activate entries again.
+			pressed := event.Buttons()&tcell.Button1 != 0 && loop.lastButtons&tcell.Button1 == 0
+			loop.lastButtons = event.Buttons()
+			if !pressed {
+				continue
+			}
+			mouseX, mouseY := event.Position()
+			if entryIndex, hit := renderer.menuEntryAt(targetScreen, menuState, mouseX, mouseY); hit {
+				menuState.message = ""
+				menuState.selectedEntry = entryIndex
+				if closeMenu, quit := activateSelection(); closeMenu || quit {
+					return quit
 				}
-			} else if event.Key() == tcell.KeyEnter {
-				//We clear in order to get rid of the menu for sure.
-				targetScreen.Clear()
-				break MENU_KEY_LOOP
-				//Implicitly proceed.
-			} else if event.Key() == tcell.KeyCtrlC {
-				targetScreen.Fini()
-				os.Exit(0)
+			}
+		default:
+			//Unsupported or irrelevant event
+		}
+	}
+}
+
+// openHighScores draws the high score screen and listens for keyboard input.
+// The leaderboard of each difficulty and mode can be viewed by cycling
+// through them. This method blocks until the user goes back to the menu or
+// wants to quit, in which case true is returned.
+func (loop *gameLoop) openHighScores() bool {
+	menuState, keys := loop.menuState, loop.keys
+	for {
+		leaderboardCount := len(menuState.leaderboards)
+		loop.renderer.drawHighScores(loop.screen, loop.end.scores, menuState.leaderboards[menuState.selectedHighScores])
+
+		screenEvent, open := <-loop.events
+		if !open {
+			return true
+		}
+		switch event := screenEvent.(type) {
+		case *tcell.EventKey:
+			if keys.is(event, rightAction) {
+				menuState.selectedHighScores = (menuState.selectedHighScores + 1) % leaderboardCount
+			} else if keys.is(event, leftAction) {
+				menuState.selectedHighScores = (menuState.selectedHighScores - 1 + leaderboardCount) % leaderboardCount
+			} else if keys.is(event, surrenderAction) || keys.is(event, selectAction) {
+				return false
+			} else if keys.is(event, quitAction) {
+				return true
+			}
+		default:
+			//Unsupported or irrelevant event
+		}
+	}
+}
+
+// openStats draws the statistics screen and blocks until the user goes back
+// to the menu or wants to quit, in which case true is returned.
+func (loop *gameLoop) openStats() bool {
+	keys := loop.keys
+	for {
+		loop.renderer.drawStats(loop.screen, loop.end.stats)
+
+		screenEvent, open := <-loop.events
+		if !open {
+			return true
+		}
+		switch event := screenEvent.(type) {
+		case *tcell.EventKey:
+			if keys.is(event, surrenderAction) || keys.is(event, selectAction) {
+				return false
+			} else if keys.is(event, quitAction) {
+				return true
 			}
 		default:
 			//Unsupported or irrelevant event
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestGameLoop plays through the menu and restarts the session a couple of
+// times while runes are being hidden. It's mainly useful when run with
+// -race, as the session is replaced while the old one might still be busy.
+func TestGameLoop(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	scores, _ := loadHighScores("")
+	stats, _ := loadStats("")
+	adaptive, _ := loadAdaptiveTrainer("")
+	loop := &gameLoop{
+		screen:    screen,
+		events:    pollEvents(screen),
+		renderer:  newRenderer(defaultKeymap(), darkTheme),
+		keys:      defaultKeymap(),
+		menuState: newMenuState(darkTheme, adaptive),
+		end:       &endScreen{scores: scores, stats: stats, adaptive: adaptive},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		loop.run()
+	}()
+
+	key := func(key tcell.Key, r rune) {
+		screen.PostEventWait(tcell.NewEventKey(key, r, tcell.ModNone))
+	}
+
+	//Starts the selected difficulty, which is "normal" by default.
+	key(tcell.KeyEnter, 0)
+	for i := 0; i < 10; i++ {
+		key(tcell.KeyRune, rune('0'+i))
+		key(tcell.KeyCtrlR, 0)
+		screen.PostEventWait(tcell.NewEventResize(100, 40))
+		key(tcell.KeyCtrlP, 0)
+		key(tcell.KeyCtrlP, 0)
+	}
+	key(tcell.KeyCtrlC, 0)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the game loop didn't quit")
+	}
+	if loop.session == nil || loop.session.Snapshot().Difficulty.VisibleName != "normal" {
+		t.Fatal("the game loop didn't play the selected difficulty")
+	}
+	//The restarts have replaced the session several times. The latest one
+	//mustn't keep running once the loop has returned.
+	if loop.sessionContext.Err() == nil {
+		t.Error("the context of the latest session hasn't been cancelled")
+	}
+	if loop.session.Snapshot().State == engine.Ongoing {
+		t.Error("the latest session hasn't been ended")
+	}
+
+	//Without the rune hiding coroutine, the session only changes if
+	//drawing or checking the screen size changes it. Each change would
+	//cause another frame, so changing it every time would keep the loop
+	//busy.
+	if startError := loop.startSession(); startError != nil {
+		t.Fatal(startError)
+	}
+	loop.cancelSession()
+	updates := loop.session.Subscribe()
+	expectUpdates := func(expected bool, situation string) {
+		for i := 0; i < 3; i++ {
+			loop.checkScreenSize()
+			loop.draw()
+		}
+		select {
+		case <-updates:
+			if !expected {
+				t.Errorf("the session has changed while %s", situation)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if expected {
+				t.Errorf("the session hasn't changed while %s", situation)
+			}
+		}
+	}
+	expectUpdates(false, "nothing happened")
+	screen.SetSize(10, 5)
+	expectUpdates(true, "the screen became too small")
+	expectUpdates(false, "the screen stayed too small")
+	screen.SetSize(100, 40)
+	expectUpdates(true, "the screen was enlarged")
+	expectUpdates(false, "the screen stayed large enough")
+}
+
+// TestMenuReactsToMousePresses makes sure that holding the button or
+// dragging the mouse across the menu doesn't activate an entry again.
+func TestMenuReactsToMousePresses(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	adaptive, _ := loadAdaptiveTrainer("")
+	events := make(chan tcell.Event, 10)
+	loop := &gameLoop{
+		screen:    screen,
+		events:    events,
+		renderer:  newRenderer(defaultKeymap(), darkTheme),
+		keys:      defaultKeymap(),
+		menuState: newMenuState(darkTheme, adaptive),
+	}
+
+	themeEntryIndex := len(loop.menuState.entries) - 1
+	themeX, themeY := -1, -1
+	for y := 0; y < 40 && themeX == -1; y++ {
+		for x := 0; x < 100; x++ {
+			if index, hit := loop.renderer.menuEntryAt(screen, loop.menuState, x, y); hit && index == themeEntryIndex {
+				themeX, themeY = x, y
+				break
+			}
+		}
+	}
+	if themeX == -1 {
+		t.Fatal("the theme entry couldn't be found")
+	}
+
+	//Pressing, holding, dragging along the entry and releasing.
+	events <- tcell.NewEventMouse(themeX, themeY, tcell.Button1, tcell.ModNone)
+	events <- tcell.NewEventMouse(themeX, themeY, tcell.Button1, tcell.ModNone)
+	events <- tcell.NewEventMouse(themeX+1, themeY, tcell.Button1, tcell.ModNone)
+	events <- tcell.NewEventMouse(themeX+1, themeY, tcell.ButtonNone, tcell.ModNone)
+	close(events)
+
+	if quit := loop.openMenu(); !quit {
+		t.Fatal("the menu was closed without quitting")
+	}
+	if expected := nextTheme(darkTheme); loop.menuState.theme != expected {
+		t.Errorf("expected theme %s, got %s", expected.name, loop.menuState.theme.name)
+	}
+}
+
+// TestRestartChecksKeymap makes sure that every new session is checked for
+// keys that have to be typed on the board, not only the first one started
+// via the menu.
+func TestRestartChecksKeymap(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	keys := defaultKeymap()
+	keys[pauseAction] = []keyBinding{{key: tcell.KeyRune, r: 'p'}}
+	adaptive, _ := loadAdaptiveTrainer("")
+	events := make(chan tcell.Event)
+	close(events)
+	loop := &gameLoop{
+		screen:    screen,
+		events:    events,
+		renderer:  newRenderer(keys, darkTheme),
+		keys:      keys,
+		menuState: newMenuState(darkTheme, adaptive),
+	}
+	selectEntry := func(name string) {
+		for index, entry := range loop.menuState.entries {
+			if entry.visibleName == name {
+				loop.menuState.selectedEntry = index
+				return
+			}
+		}
+		t.Fatalf("there's no entry called %s", name)
+	}
+
+	selectEntry("normal")
+	if startError := loop.startSession(); startError != nil {
+		t.Fatal(startError)
+	}
+	defer loop.cancelSession()
+
+	//The entry now uses letters, like the adaptive difficulty does on
+	//higher levels.
+	selectEntry("hard")
+	if quit := loop.handleEvent(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModNone)); !quit {
+		t.Fatal("the menu wasn't opened after restarting")
+	}
+	if loop.session.Snapshot().Difficulty.VisibleName != "normal" {
+		t.Error("a session using the conflicting key has been started")
+	}
+	if loop.menuState.message == "" {
+		t.Error("the menu doesn't explain why the game couldn't be restarted")
+	}
+}
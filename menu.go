@@ -1,17 +1,215 @@
 package main
 
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+type menuEntryKind int
+
+const (
+	//playEntry starts a game using the entry's difficulty.
+	playEntry menuEntryKind = iota
+	//adaptiveEntry starts a game using the difficulty of the player's
+	//current adaptive level.
+	adaptiveEntry
+	//dailyChallengeEntry starts a game using the entry's difficulty and a
+	//seed that is derived from the current date.
+	dailyChallengeEntry
+	//highScoresEntry opens the high score screen.
+	highScoresEntry
+	//statsEntry opens the statistics screen.
+	statsEntry
+	//themeEntry switches to the next theme.
+	themeEntry
+)
+
+// dailyChallengeDifficulty is the name of the difficulty used for the daily
+// challenge. It has to be a built-in one, so that every player has it.
+const dailyChallengeDifficulty = "normal"
+
+// menuEntry represents a single selectable line in the main menu.
+type menuEntry struct {
+	visibleName string
+	kind        menuEntryKind
+	//difficulty is only set for entries that start a game, except for the
+	//adaptive entry, which takes it from the trainer.
+	difficulty *engine.Difficulty
+}
+
 type menuState struct {
-	selectedDifficulty int
+	entries       []*menuEntry
+	selectedEntry int
+	//selectedMode is the index of the mode in engine.Modes that is used for
+	//all games started via the menu, except for the daily challenge.
+	selectedMode int
+
+	//leaderboards contains the names of all high score tables, one for
+	//each combination of difficulty and mode.
+	leaderboards []string
+	//selectedHighScores is the index of the leaderboard shown on the high
+	//score screen.
+	selectedHighScores int
+
+	//theme is used for drawing the board of all games started via the
+	//menu.
+	theme *theme
+	//adaptive decides the difficulty of the adaptive entry.
+	adaptive *adaptiveTrainer
+
+	//message explains why the selected entry can't be started. It's reset
+	//on the next key press.
+	message string
 }
 
-func newMenuState() *menuState {
+// newMenuState creates a menu containing one entry per difficulty and the
+// adaptive entry, followed by the entries that don't start a game.
+// Therefore all custom difficulties have to be loaded beforehand.
+func newMenuState(chosenTheme *theme, adaptive *adaptiveTrainer) *menuState {
+	entries := make([]*menuEntry, 0, len(difficulties)+5)
+	for _, diff := range difficulties {
+		entries = append(entries, &menuEntry{
+			visibleName: diff.VisibleName,
+			kind:        playEntry,
+			difficulty:  diff,
+		})
+	}
+	entries = append(entries, &menuEntry{
+		visibleName: adaptive.description(),
+		kind:        adaptiveEntry,
+	})
+	entries = append(entries, &menuEntry{
+		visibleName: "Daily challenge",
+		kind:        dailyChallengeEntry,
+		difficulty:  findDifficulty(dailyChallengeDifficulty),
+	})
+	entries = append(entries, &menuEntry{
+		visibleName: "High scores",
+		kind:        highScoresEntry,
+	})
+	entries = append(entries, &menuEntry{
+		visibleName: "Statistics",
+		kind:        statsEntry,
+	})
+	entries = append(entries, &menuEntry{
+		visibleName: themeEntryName(chosenTheme),
+		kind:        themeEntry,
+	})
+
+	leaderboards := make([]string, 0, len(difficulties)*len(engine.Modes)+1)
+	for _, mode := range engine.Modes {
+		//Hot-seat games aren't added to the high scores.
+		if mode == engine.HotSeatMode {
+			continue
+		}
+		for _, diff := range difficulties {
+			leaderboards = append(leaderboards, leaderboardName(diff.VisibleName, mode))
+		}
+	}
+	//Adaptive games are always played in classic mode.
+	leaderboards = append(leaderboards, leaderboardName(adaptiveDifficultyName, engine.ClassicMode))
+
 	return &menuState{
+		entries: entries,
 		//Default difficulty normal
-		selectedDifficulty: 1,
+		selectedEntry: 1,
+
+		leaderboards:       leaderboards,
+		selectedHighScores: 1,
+
+		theme:    chosenTheme,
+		adaptive: adaptive,
+	}
+}
+
+// themeEntryName returns the name of the menu entry for switching themes,
+// which shows the current theme.
+func themeEntryName(current *theme) string {
+	return "Theme: " + current.name
+}
+
+// selectNextTheme switches to the next theme and updates the menu entry
+// showing it.
+func (menuState *menuState) selectNextTheme() {
+	menuState.theme = nextTheme(menuState.theme)
+	for _, entry := range menuState.entries {
+		if entry.kind == themeEntry {
+			entry.visibleName = themeEntryName(menuState.theme)
+		}
+	}
+}
+
+// refreshAdaptiveEntry updates the menu entry of the adaptive difficulty,
+// so that it shows the player's current level.
+func (menuState *menuState) refreshAdaptiveEntry() {
+	for _, entry := range menuState.entries {
+		if entry.kind == adaptiveEntry {
+			entry.visibleName = menuState.adaptive.description()
+		}
+	}
+}
+
+// selectNextMode switches to the next game mode, wrapping around at the end.
+func (menuState *menuState) selectNextMode() {
+	menuState.selectedMode = (menuState.selectedMode + 1) % len(engine.Modes)
+}
+
+// selectPreviousMode switches to the previous game mode, wrapping around at
+// the start.
+func (menuState *menuState) selectPreviousMode() {
+	menuState.selectedMode = (menuState.selectedMode - 1 + len(engine.Modes)) % len(engine.Modes)
+}
+
+// getMode returns the game mode for the selected entry. The daily challenge
+// is always played in classic mode, so that all scores are comparable. The
+// adaptive difficulty is only tuned for classic mode.
+func (menuState *menuState) getMode() engine.Mode {
+	if kind := menuState.getSelectedEntry().kind; kind == dailyChallengeEntry || kind == adaptiveEntry {
+		return engine.ClassicMode
+	}
+
+	return engine.Modes[menuState.selectedMode]
+}
+
+// selectNext moves the selection down, wrapping around at the end.
+func (menuState *menuState) selectNext() {
+	if menuState.selectedEntry >= len(menuState.entries)-1 {
+		menuState.selectedEntry = 0
+	} else {
+		menuState.selectedEntry++
+	}
+}
+
+// selectPrevious moves the selection up, wrapping around at the start.
+func (menuState *menuState) selectPrevious() {
+	if menuState.selectedEntry <= 0 {
+		menuState.selectedEntry = len(menuState.entries) - 1
+	} else {
+		menuState.selectedEntry--
 	}
 }
 
+// getSelectedEntry returns the entry currently highlighted in the menu.
+func (menuState *menuState) getSelectedEntry() *menuEntry {
+	return menuState.entries[menuState.selectedEntry]
+}
+
 // getDiffculty returns the diffculty chosen by the user.
-func (menuState *menuState) getDiffculty() *difficulty {
-	return difficulties[menuState.selectedDifficulty]
+func (menuState *menuState) getDiffculty() *engine.Difficulty {
+	if menuState.getSelectedEntry().kind == adaptiveEntry {
+		return menuState.adaptive.difficulty()
+	}
+
+	return menuState.getSelectedEntry().difficulty
+}
+
+// dailySeed derives a seed from the date of the given point in time. The
+// date is taken in UTC, so that all players get the same board, no matter
+// which timezone they're in.
+func dailySeed(now time.Time) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(now.UTC().Format("2006-01-02")))
+	return int64(hash.Sum64())
 }
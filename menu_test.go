@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+func TestDailySeed(t *testing.T) {
+	morning := time.Date(2020, 6, 1, 0, 30, 0, 0, time.UTC)
+	evening := time.Date(2020, 6, 1, 23, 30, 0, 0, time.UTC)
+	if dailySeed(morning) != dailySeed(evening) {
+		t.Error("daily seed changed within a day")
+	}
+	if dailySeed(morning) == dailySeed(morning.AddDate(0, 0, 1)) {
+		t.Error("daily seed didn't change on the next day")
+	}
+}
+
+func TestLeaderboardName(t *testing.T) {
+	if name := leaderboardName("easy", engine.ClassicMode); name != "easy" {
+		t.Errorf("unexpected leaderboard name %s", name)
+	}
+	if name := leaderboardName("easy", engine.EndlessMode); name != "easy (endless)" {
+		t.Errorf("unexpected leaderboard name %s", name)
+	}
+}
@@ -2,27 +2,105 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
-	"github.com/gdamore/tcell"
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
 )
 
 const (
 	chooseDifficultyText = "Choose difficulty"
 	gameOverMessage      = "GAME OVER"
 	victoryMessage       = "Congratulations! You have won!"
-	restartMessage       = "Hit 'Ctrl R' to restart or 'ESC' to show the menu."
+	restartMessage       = "Hit '%s' to restart or '%s' to show the menu."
+	replayEndMessage     = "The replay has finished. Hit '%s' to quit."
+	leaveDuelMessage     = "Hit '%s' to leave the duel."
+	highScoresTitle      = "High scores"
+	highScoresHint       = "Use '%s' / '%s' to switch leaderboards and '%s' to go back."
+	noHighScoresMessage  = "No games have been played on this difficulty yet."
+	modeHint             = "Use '%s' / '%s' to switch the game mode."
+	statsTitle           = "Statistics"
+	statsHint            = "Hit '%s' to go back."
+
+	fullBlock  = '█'
+	checkMark  = '✓'
+	lightShade = '░'
+
+	//hiddenGaugeWidth is the amount of characters used for the gauge
+	//showing how close the player is to losing.
+	hiddenGaugeWidth = 20
+	//statusAreaHeight is the amount of lines above the board that are
+	//reserved for the status lines.
+	statusAreaHeight = 5
+	//statusAreaWidth is the width required to fit the status lines.
+	statusAreaWidth = 50
+	//opponentPanelWidth is the width reserved for the opponent's progress
+	//at the right edge of the screen during duels.
+	opponentPanelWidth = 26
+
+	enlargeTerminalMessage = "Please enlarge your terminal"
+	pausedMessage          = "PAUSED"
+	resumeMessage          = "Hit '%s' to resume."
+	pairsPreviewHint       = "Remember the board, it will be hidden soon."
+	pairsHint              = "Type a column letter and a row number, e.g. 'b2'."
+	sequenceWatchHint      = "Watch the order in which the cells are hidden."
+)
 
-	fullBlock = '█'
-	checkMark = '✓'
+const (
+	//menuEntriesY is the line on which the first menu entry is drawn.
+	menuEntriesY = 7
+	//menuEntrySpacing is the distance between the lines of two menu
+	//entries.
+	menuEntrySpacing = 2
 )
 
 var titleStyle = tcell.StyleDefault.Bold(true)
 
+// endScreen holds the information shown after a session has ended, which
+// isn't part of the session itself.
+type endScreen struct {
+	scores *highScoreTable
+	//lastScore is the entry of the last session, if it has made it into
+	//the high scores. It's highlighted, so the player can find it.
+	lastScore *highScoreEntry
+	//scoresSaveError is the error produced by saving the high scores after
+	//the last session.
+	scoresSaveError error
+	//player is added to the high score entries, so that players sharing a
+	//leaderboard can tell their results apart. It's empty for local games.
+	player string
+	stats  *playerStats
+	//statsSaveError is the error produced by saving the statistics after
+	//the last session.
+	statsSaveError error
+	adaptive       *adaptiveTrainer
+	//adaptiveSaveError is the error produced by saving the adaptive level
+	//after the last session.
+	adaptiveSaveError error
+	//duel is only set for duels and replaces the high scores with the
+	//opponent's result.
+	duel *duelStatus
+	//saveReplays decides whether recordings are saved. Players connected
+	//via SSH couldn't access them, so they aren't saved for them.
+	saveReplays bool
+	//replayPath is the file the last session's recording was saved to.
+	replayPath      string
+	replaySaveError error
+}
+
 // renderer represents a utility object to present a gameSession on a
 // terminal screen.
 type renderer struct {
 	horizontalSpacing int
 	verticalSpacing   int
+	//keys is used for telling the player which keys to press.
+	keys keymap
+	//theme defines the styles of the board's cells.
+	theme *theme
+	//showHideOrder decides whether the end screen annotates each cell
+	//with the order in which it was hidden.
+	showHideOrder bool
 }
 
 // newRenderer creates a new reusable renderer. It can be used for any
@@ -30,10 +108,12 @@ type renderer struct {
 // The renderer itself is stateless, which is why it can be used for
 // multiple sessions and screens. Technically, you could draw on multiple
 // screens at once.
-func newRenderer() *renderer {
+func newRenderer(keys keymap, chosenTheme *theme) *renderer {
 	return &renderer{
 		horizontalSpacing: 2,
 		verticalSpacing:   1,
+		keys:              keys,
+		theme:             chosenTheme,
 	}
 }
 
@@ -46,8 +126,8 @@ func (r *renderer) drawMenu(targetScreen tcell.Screen, sourceMenuState *menuStat
 	unselectedStyle := tcell.StyleDefault
 	selectedStyle := tcell.StyleDefault.Reverse(true)
 
-	determineStyle := func(difficulty int) tcell.Style {
-		if sourceMenuState.selectedDifficulty == difficulty {
+	determineStyle := func(entry int) tcell.Style {
+		if sourceMenuState.selectedEntry == entry {
 			return selectedStyle
 		}
 
@@ -60,17 +140,50 @@ func (r *renderer) drawMenu(targetScreen tcell.Screen, sourceMenuState *menuStat
 	r.printStyledLine(targetScreen, chooseDifficultyText, titleStyle,
 		getHorizontalCenterForText(screenWidth, chooseDifficultyText), 2)
 
-	//Draw difficulties into menu.
-	nextY := 4
-	for diffIndex, diff := range difficulties {
-		r.printStyledLine(targetScreen, diff.visibleName, determineStyle(diffIndex),
-			getHorizontalCenterForText(screenWidth, diff.visibleName), nextY)
-		nextY += 2
+	//Draw mode selection
+	modeText := fmt.Sprintf("Mode: < %s >", engine.Modes[sourceMenuState.selectedMode])
+	r.printLine(targetScreen, modeText, getHorizontalCenterForText(screenWidth, modeText), 4)
+	modeHintText := fmt.Sprintf(modeHint, r.keys.name(leftAction), r.keys.name(rightAction))
+	r.printLine(targetScreen, modeHintText, getHorizontalCenterForText(screenWidth, modeHintText), 5)
+
+	//Draw difficulties and other entries into menu.
+	nextY := menuEntriesY
+	for entryIndex, entry := range sourceMenuState.entries {
+		r.printStyledLine(targetScreen, entry.visibleName, determineStyle(entryIndex),
+			getHorizontalCenterForText(screenWidth, entry.visibleName), nextY)
+		nextY += menuEntrySpacing
+	}
+	if sourceMenuState.message != "" {
+		r.printLine(targetScreen, sourceMenuState.message,
+			getHorizontalCenterForText(screenWidth, sourceMenuState.message), nextY)
 	}
 
 	targetScreen.Show()
 }
 
+// menuEntryAt returns the index of the menu entry drawn at the given screen
+// position. If there's no entry, false is returned.
+func (r *renderer) menuEntryAt(targetScreen tcell.Screen, sourceMenuState *menuState, screenX, screenY int) (int, bool) {
+	offsetY := screenY - menuEntriesY
+	if offsetY < 0 || offsetY%menuEntrySpacing != 0 {
+		return 0, false
+	}
+
+	entryIndex := offsetY / menuEntrySpacing
+	if entryIndex >= len(sourceMenuState.entries) {
+		return 0, false
+	}
+
+	screenWidth, _ := targetScreen.Size()
+	entry := sourceMenuState.entries[entryIndex]
+	entryX := getHorizontalCenterForText(screenWidth, entry.visibleName)
+	if screenX < entryX || screenX >= entryX+len(entry.visibleName) {
+		return 0, false
+	}
+
+	return entryIndex, true
+}
+
 // getHorizontalCenterForText returns the x-coordinate at which the caller must
 // start drawing in order to horizontally center given text. Note that this
 // function doesn't take rune-width into count, as it is currently irrelevant.
@@ -78,68 +191,582 @@ func getHorizontalCenterForText(screenWidth int, text string) int {
 	return screenWidth/2 - len(text)/2
 }
 
-// drawGameBoard fills the targetScreen with data from the passed gameSession.
-func (r *renderer) drawGameBoard(targetScreen tcell.Screen, session *gameSession) {
-	boardWidth := (session.difficulty.rowCount / 2 * (r.horizontalSpacing + 1))
-	boardHeight := (session.difficulty.columnCount / 2 * (r.verticalSpacing + 1))
+// drawHighScores draws the leaderboard with the given name.
+func (r *renderer) drawHighScores(targetScreen tcell.Screen, scores *highScoreTable, leaderboard string) {
+	targetScreen.Clear()
+
+	screenWidth, _ := targetScreen.Size()
+
+	title := fmt.Sprintf("%s - < %s >", highScoresTitle, leaderboard)
+	r.printStyledLine(targetScreen, title, titleStyle,
+		getHorizontalCenterForText(screenWidth, title), 2)
+	hint := fmt.Sprintf(highScoresHint, r.keys.name(leftAction), r.keys.name(rightAction),
+		r.keys.name(surrenderAction))
+	r.printLine(targetScreen, hint, getHorizontalCenterForText(screenWidth, hint), 4)
+	r.printHighScoreTable(targetScreen, screenWidth, scores.get(leaderboard), nil, 6)
+
+	targetScreen.Show()
+}
+
+// drawStats draws the statistics of all games played so far.
+func (r *renderer) drawStats(targetScreen tcell.Screen, stats *playerStats) {
+	targetScreen.Clear()
+
+	screenWidth, _ := targetScreen.Size()
+
+	r.printStyledLine(targetScreen, statsTitle, titleStyle,
+		getHorizontalCenterForText(screenWidth, statsTitle), 2)
+	hint := fmt.Sprintf(statsHint, r.keys.name(surrenderAction))
+	r.printLine(targetScreen, hint, getHorizontalCenterForText(screenWidth, hint), 4)
+
+	//The lines form a table, therefore they all have to start at the same
+	//column.
+	lines := stats.lines()
+	var widestLine string
+	for _, line := range lines {
+		if len(line) > len(widestLine) {
+			widestLine = line
+		}
+	}
+	x := getHorizontalCenterForText(screenWidth, widestLine)
+	for index, line := range lines {
+		r.printLine(targetScreen, line, x, 6+index)
+	}
+
+	targetScreen.Show()
+}
+
+// printHighScoreTable prints one line per entry, starting at the given
+// y-coordinate. The highlighted entry is drawn reversed.
+func (r *renderer) printHighScoreTable(targetScreen tcell.Screen, screenWidth int,
+	entries []*highScoreEntry, highlighted *highScoreEntry, y int) {
+	if len(entries) == 0 {
+		r.printLine(targetScreen, noHighScoresMessage,
+			getHorizontalCenterForText(screenWidth, noHighScoresMessage), y)
+		return
+	}
+
+	for index, entry := range entries {
+		line := fmt.Sprintf("%2d. %5d points %3d invalid %-9s %6s %s",
+			index+1, entry.Score, entry.InvalidKeyPresses, entry.Outcome,
+			formatDuration(entry.Duration), entry.Timestamp.Local().Format("2006-01-02 15:04"))
+		if entry.Player != "" {
+			line += " " + entry.Player
+		}
+		style := tcell.StyleDefault
+		if entry == highlighted {
+			style = style.Reverse(true)
+		}
+		r.printStyledLine(targetScreen, line, style,
+			getHorizontalCenterForText(screenWidth, line), y+index)
+	}
+}
+
+// formatDuration formats the duration as minutes and seconds, e.g. "1:05".
+func formatDuration(duration time.Duration) string {
+	seconds := int(duration.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// drawGameBoard fills the targetScreen with data from the passed snapshot.
+// Once the game is over, the information of the end screen is shown as
+// well. If there's no end screen, the session is assumed to be a replay.
+func (r *renderer) drawGameBoard(targetScreen tcell.Screen, snapshot engine.Snapshot, end *endScreen) {
+	//As the status line changes its length, we'd get left-overs otherwise.
+	targetScreen.Clear()
 
 	width, height := targetScreen.Size()
 
+	//Once the game is over, we draw whatever fits, as nothing can be
+	//missed anymore.
+	duel := end != nil && end.duel != nil
+	if snapshot.State == engine.Ongoing && !r.fitsOnScreen(targetScreen, snapshot.Difficulty, snapshot.Mode, duel) {
+		r.printEnlargeTerminalMessage(targetScreen, snapshot.Difficulty, snapshot.Mode, duel)
+		targetScreen.Show()
+		return
+	}
+
+	//The board is covered, so it can't be studied while the game is paused.
+	if snapshot.State == engine.Ongoing && snapshot.Paused {
+		r.printStatusLines(width, targetScreen, snapshot)
+		r.printStyledLine(targetScreen, pausedMessage, titleStyle,
+			getHorizontalCenterForText(width, pausedMessage), height/2-1)
+		resumeText := fmt.Sprintf(resumeMessage, r.keys.name(pauseAction))
+		r.printLine(targetScreen, resumeText, getHorizontalCenterForText(width, resumeText), height/2+1)
+		targetScreen.Show()
+		return
+	}
+
 	//Draw gameBoard to screen. This block contains no game-logic.
 	//We draw this regardless of the game state, since the player
 	//wouldn't be able to see the effect of their last move otherwise.
-	nextY := height/2 - boardHeight
-	for y := 0; y < session.difficulty.columnCount; y++ {
-		nextX := width/2 - boardWidth
-		for x := 0; x < session.difficulty.rowCount; x++ {
+	boardX, nextY := r.boardOrigin(targetScreen, snapshot.Difficulty)
+	//In pairs mode, cells are addressed via their coordinates, therefore
+	//the column letters and row numbers are drawn next to the board.
+	if snapshot.Mode == engine.PairsMode {
+		r.printCoordinateLabels(targetScreen, snapshot.Difficulty, boardX, nextY)
+	}
+	cellTheme := effectiveTheme(targetScreen, r.theme)
+	for y := 0; y < snapshot.Difficulty.ColumnCount; y++ {
+		nextX := boardX
+		for x := 0; x < snapshot.Difficulty.RowCount; x++ {
 			var renderRune rune
-			boardCell := session.gameBoard[x+(session.difficulty.rowCount*y)]
-			switch boardCell.state {
-			case shown:
-				renderRune = boardCell.character
-			case hidden:
-				renderRune = fullBlock
-			case guessed:
+			var style tcell.Style
+			boardCell := snapshot.Board[x+(snapshot.Difficulty.RowCount*y)]
+			switch boardCell.State {
+			case engine.Shown:
+				renderRune = boardCell.Character
+				style = cellTheme.shown
+			case engine.Hidden:
+				//Cells that are still hidden once the game is over
+				//have been missed by the player. They're revealed, so
+				//the player learns what they've forgotten.
+				if snapshot.State == engine.Ongoing {
+					renderRune = fullBlock
+					style = cellTheme.hidden
+				} else {
+					renderRune = boardCell.Character
+					style = cellTheme.missed
+				}
+			case engine.Guessed:
 				renderRune = checkMark
+				style = cellTheme.guessed
+			case engine.Revealed:
+				renderRune = boardCell.Character
+				style = cellTheme.revealed
 			}
 
-			targetScreen.SetContent(nextX, nextY, renderRune, nil, tcell.StyleDefault)
+			targetScreen.SetContent(nextX, nextY, renderRune, nil, style)
+			if r.showHideOrder && snapshot.State != engine.Ongoing {
+				r.printHideOrder(targetScreen, boardCell, nextX+1, nextY)
+			}
 			nextX += r.horizontalSpacing + 1
 		}
 		nextY += r.verticalSpacing + 1
 	}
 
-	switch session.state {
-	case victory:
+	switch snapshot.State {
+	case engine.Victory:
 		r.printStyledLine(targetScreen, victoryMessage, titleStyle, width/2-len(victoryMessage)/2, 2)
-	case gameOver:
+	case engine.GameOver:
 		r.printStyledLine(targetScreen, gameOverMessage, titleStyle, width/2-len(gameOverMessage)/2, 2)
 	}
+	if endReasonMessage := createEndReasonMessage(snapshot); endReasonMessage != "" {
+		r.printLine(targetScreen, endReasonMessage, width/2-len(endReasonMessage)/2, 3)
+	}
 
-	if session.state != ongoing {
-		r.printGameResults(width, targetScreen, session)
+	if snapshot.State == engine.Ongoing {
+		r.printStatusLines(width, targetScreen, snapshot)
+	} else {
+		r.printGameResults(width, targetScreen, snapshot, end)
+	}
+	if duel {
+		r.printOpponentPanel(targetScreen, snapshot, end.duel)
 	}
 
 	targetScreen.Show()
 }
 
+// printHideOrder prints the position at which the cell has been hidden
+// right next to it. Cells that haven't been hidden aren't annotated.
+func (r *renderer) printHideOrder(targetScreen tcell.Screen, boardCell engine.Cell, x, y int) {
+	if boardCell.HideOrder == 0 {
+		return
+	}
+
+	//The annotation has to fit into the spacing, as it would cover the
+	//next cell otherwise.
+	if order := strconv.Itoa(boardCell.HideOrder); len(order) <= r.horizontalSpacing {
+		r.printStyledLine(targetScreen, order, tcell.StyleDefault.Dim(true), x, y)
+	}
+}
+
+// boardOrigin returns the screen position of the top left cell of a board
+// using the given difficulty. The board is centered on the screen.
+func (r *renderer) boardOrigin(targetScreen tcell.Screen, diff *engine.Difficulty) (int, int) {
+	width, height := targetScreen.Size()
+	boardWidth := diff.RowCount / 2 * (r.horizontalSpacing + 1)
+	boardHeight := diff.ColumnCount / 2 * (r.verticalSpacing + 1)
+	return width/2 - boardWidth, height/2 - boardHeight
+}
+
+// cellAt returns the index of the board cell drawn at the given screen
+// position. The spacing around a cell counts as part of the cell, so it
+// doesn't have to be hit exactly. If there's no cell, false is returned.
+func (r *renderer) cellAt(targetScreen tcell.Screen, diff *engine.Difficulty, screenX, screenY int) (int, bool) {
+	boardX, boardY := r.boardOrigin(targetScreen, diff)
+	offsetX := screenX - boardX + r.horizontalSpacing/2
+	offsetY := screenY - boardY + r.verticalSpacing/2
+	if offsetX < 0 || offsetY < 0 {
+		return 0, false
+	}
+
+	x := offsetX / (r.horizontalSpacing + 1)
+	y := offsetY / (r.verticalSpacing + 1)
+	if x >= diff.RowCount || y >= diff.ColumnCount {
+		return 0, false
+	}
+
+	return x + diff.RowCount*y, true
+}
+
+// printCoordinateLabels prints the column letters above and the row
+// numbers left of the board, whose top left cell is at the given position.
+func (r *renderer) printCoordinateLabels(targetScreen tcell.Screen, diff *engine.Difficulty, boardX, boardY int) {
+	labelStyle := tcell.StyleDefault.Dim(true)
+	for x := 0; x < diff.RowCount; x++ {
+		targetScreen.SetContent(boardX+x*(r.horizontalSpacing+1), boardY-1, engine.ColumnLabel(x), nil, labelStyle)
+	}
+	for y := 0; y < diff.ColumnCount; y++ {
+		targetScreen.SetContent(boardX-r.horizontalSpacing-1, boardY+y*(r.verticalSpacing+1), engine.RowLabel(y), nil, labelStyle)
+	}
+}
+
+// requiredSize returns the minimum screen size required to draw the board
+// of the given difficulty and the status lines above it. During duels, the
+// opponent panel has to fit right of the board as well.
+func (r *renderer) requiredSize(diff *engine.Difficulty, mode engine.Mode, duel bool) (int, int) {
+	boardWidth := diff.RowCount / 2 * (r.horizontalSpacing + 1)
+	boardHeight := diff.ColumnCount / 2 * (r.verticalSpacing + 1)
+	//The coordinate labels of pairs mode are drawn left of and above the
+	//board, see printCoordinateLabels.
+	if mode == engine.PairsMode {
+		boardWidth += r.horizontalSpacing + 1
+		boardHeight++
+	}
+	//The span is the distance from the first to the last character.
+	boardSpanX := (diff.RowCount-1)*(r.horizontalSpacing+1) + 1
+	boardSpanY := (diff.ColumnCount-1)*(r.verticalSpacing+1) + 1
+
+	//The board is drawn relative to the screen's center, so it has to fit
+	//on both sides of the center.
+	requiredWidth := maxInt(statusAreaWidth, 2*boardWidth, 2*(boardSpanX-boardWidth))
+	if duel {
+		requiredWidth = maxInt(requiredWidth, 2*(boardSpanX-boardWidth+opponentPanelWidth))
+	}
+	requiredHeight := maxInt(2*(boardHeight+statusAreaHeight), 2*(boardSpanY-boardHeight))
+	return requiredWidth, requiredHeight
+}
+
+// fitsOnScreen decides whether the board of the given difficulty can be
+// drawn on the screen without being cut off.
+func (r *renderer) fitsOnScreen(targetScreen tcell.Screen, diff *engine.Difficulty, mode engine.Mode, duel bool) bool {
+	width, height := targetScreen.Size()
+	requiredWidth, requiredHeight := r.requiredSize(diff, mode, duel)
+	return width >= requiredWidth && height >= requiredHeight
+}
+
+// printEnlargeTerminalMessage tells the user how big the screen has to be
+// in order to play the given difficulty.
+func (r *renderer) printEnlargeTerminalMessage(targetScreen tcell.Screen, diff *engine.Difficulty, mode engine.Mode, duel bool) {
+	width, height := targetScreen.Size()
+	requiredWidth, requiredHeight := r.requiredSize(diff, mode, duel)
+	sizeMessage := fmt.Sprintf("to at least %dx%d (currently %dx%d).", requiredWidth, requiredHeight, width, height)
+
+	r.printStyledLine(targetScreen, enlargeTerminalMessage, titleStyle,
+		getHorizontalCenterForText(width, enlargeTerminalMessage), height/2-1)
+	r.printLine(targetScreen, sizeMessage, getHorizontalCenterForText(width, sizeMessage), height/2)
+}
+
+func maxInt(first int, others ...int) int {
+	max := first
+	for _, other := range others {
+		if other > max {
+			max = other
+		}
+	}
+	return max
+}
+
+// printStatusLines prints the current score, the elapsed time, the amount
+// of cells left to hide and a gauge showing how close the player is to
+// losing due to too many hidden cells. In hot-seat mode, the score of each
+// player and whose turn it is are shown instead.
+func (r *renderer) printStatusLines(width int, targetScreen tcell.Screen, snapshot engine.Snapshot) {
+	if snapshot.Mode == engine.PairsMode {
+		r.printPairsStatusLines(width, targetScreen, snapshot)
+		return
+	}
+	if snapshot.Mode == engine.SequenceMode {
+		r.printSequenceStatusLines(width, targetScreen, snapshot)
+		return
+	}
+
+	statusMessage := fmt.Sprintf("Score: %d   Time: %s   Left to hide: %d",
+		snapshot.Score, formatDuration(snapshot.Duration), snapshot.CellsLeftToHide)
+	if snapshot.Mode == engine.EndlessMode {
+		statusMessage = fmt.Sprintf("Round: %d   %s", snapshot.Round, statusMessage)
+	}
+	//Both players' scores wouldn't fit next to the other information.
+	if snapshot.Mode == engine.HotSeatMode {
+		statusMessage = fmt.Sprintf("Player 1: %d   Player 2: %d   Time: %s",
+			snapshot.Players[0].Score, snapshot.Players[1].Score, formatDuration(snapshot.Duration))
+		turnMessage := fmt.Sprintf("Player %d's turn   Left to hide: %d", snapshot.CurrentPlayer+1, snapshot.CellsLeftToHide)
+		r.printStyledLine(targetScreen, turnMessage, titleStyle, width/2-len(turnMessage)/2, 4)
+	}
+	r.printLine(targetScreen, statusMessage, width/2-len(statusMessage)/2, 2)
+
+	hiddenCellCount := snapshot.HiddenCellCount
+	hiddenCellLimit := snapshot.HiddenCellLimit
+	//Without a limit, there's nothing to fill the gauge up to.
+	if hiddenCellLimit == 0 {
+		gaugeMessage := fmt.Sprintf("Hidden: %d (no limit)", hiddenCellCount)
+		r.printLine(targetScreen, gaugeMessage, width/2-len(gaugeMessage)/2, 3)
+		return
+	}
+	filled := hiddenGaugeWidth * hiddenCellCount / hiddenCellLimit
+	gauge := make([]rune, 0, hiddenGaugeWidth)
+	for i := 0; i < hiddenGaugeWidth; i++ {
+		if i < filled {
+			gauge = append(gauge, fullBlock)
+		} else {
+			gauge = append(gauge, lightShade)
+		}
+	}
+	gaugeMessage := fmt.Sprintf("Hidden [%s] %d / %d", string(gauge), hiddenCellCount, hiddenCellLimit)
+	//The gauge contains multi-byte runes, so len can't be used for centering.
+	r.printLine(targetScreen, gaugeMessage, width/2-len([]rune(gaugeMessage))/2, 3)
+}
+
+// printPairsStatusLines prints the current score, the elapsed time and the
+// amount of pairs left to find. Underneath, the player is told what to do
+// next, as pairs are played via coordinates.
+func (r *renderer) printPairsStatusLines(width int, targetScreen tcell.Screen, snapshot engine.Snapshot) {
+	var unmatchedCellCount int
+	for _, cell := range snapshot.Board {
+		if cell.State != engine.Guessed {
+			unmatchedCellCount++
+		}
+	}
+	statusMessage := fmt.Sprintf("Score: %d   Time: %s   Pairs left: %d",
+		snapshot.Score, formatDuration(snapshot.Duration), unmatchedCellCount/2)
+	r.printLine(targetScreen, statusMessage, width/2-len(statusMessage)/2, 2)
+
+	var hint string
+	if snapshot.CellsLeftToHide > 0 {
+		hint = pairsPreviewHint
+	} else if snapshot.PendingColumn != 0 {
+		hint = fmt.Sprintf("Selected column %c, type the row number.", snapshot.PendingColumn)
+	} else {
+		hint = pairsHint
+	}
+	r.printLine(targetScreen, hint, width/2-len(hint)/2, 3)
+}
+
+// printSequenceStatusLines prints the current round, score and elapsed
+// time. Underneath, the player is told whether to watch the board or how
+// much of the sequence has been typed already.
+func (r *renderer) printSequenceStatusLines(width int, targetScreen tcell.Screen, snapshot engine.Snapshot) {
+	statusMessage := fmt.Sprintf("Round: %d   Score: %d   Time: %s",
+		snapshot.Round, snapshot.Score, formatDuration(snapshot.Duration))
+	r.printLine(targetScreen, statusMessage, width/2-len(statusMessage)/2, 2)
+
+	var hint string
+	if snapshot.CellsLeftToHide > 0 {
+		hint = sequenceWatchHint
+	} else {
+		hint = fmt.Sprintf("Type the hidden characters in order: %d / %d", snapshot.SequenceProgress, snapshot.Round)
+	}
+	r.printLine(targetScreen, hint, width/2-len(hint)/2, 3)
+}
+
 // printGameResults prints the score, amount of invalid key presses and
-// information on how to restart or get to the menu.
-func (r *renderer) printGameResults(width int, targetScreen tcell.Screen, session *gameSession) {
-	scoreMessage := r.createScoreMessage(session)
-	r.printLine(targetScreen, scoreMessage, width/2-len(scoreMessage)/2, 4)
-	invalidKeyPressesMessage := r.createInvalidKeyPressesMessage(session)
-	r.printLine(targetScreen, invalidKeyPressesMessage, width/2-len(invalidKeyPressesMessage)/2, 5)
-	r.printLine(targetScreen, restartMessage, width/2-len(restartMessage)/2, 7)
+// information on how to restart or get to the menu. Underneath the board,
+// the leaderboard is printed with the latest entry being highlighted.
+func (r *renderer) printGameResults(width int, targetScreen tcell.Screen, snapshot engine.Snapshot, end *endScreen) {
+	seedY := 6
+	if snapshot.Mode == engine.HotSeatMode {
+		r.printHotSeatResults(width, targetScreen, snapshot)
+		seedY = 7
+	} else {
+		scoreMessage := r.createScoreMessage(snapshot)
+		r.printLine(targetScreen, scoreMessage, width/2-len(scoreMessage)/2, 4)
+		invalidKeyPressesMessage := r.createInvalidKeyPressesMessage(snapshot)
+		r.printLine(targetScreen, invalidKeyPressesMessage, width/2-len(invalidKeyPressesMessage)/2, 5)
+	}
+	seedMessage := fmt.Sprintf("Seed: %d", snapshot.Seed)
+	if snapshot.Mode == engine.EndlessMode || snapshot.Mode == engine.SequenceMode {
+		seedMessage = fmt.Sprintf("Rounds survived: %d; %s", snapshot.RoundsSurvived(), seedMessage)
+	}
+	r.printLine(targetScreen, seedMessage, width/2-len(seedMessage)/2, seedY)
+	//The breakdown only adds information if guesses aren't worth the same.
+	//In hot-seat mode, the line is taken by the second player's result.
+	if snapshot.Difficulty.SpeedBonus > 0 && snapshot.Mode != engine.HotSeatMode {
+		breakdown := snapshot.ScoreBreakdown
+		breakdownMessage := fmt.Sprintf("Base: %d   Speed bonus: %d   Penalties: -%d",
+			breakdown.Base, breakdown.SpeedBonus, breakdown.Penalty)
+		r.printLine(targetScreen, breakdownMessage, width/2-len(breakdownMessage)/2, 7)
+	}
+
+	if end == nil {
+		replayEndText := fmt.Sprintf(replayEndMessage, r.keys.name(surrenderAction))
+		r.printLine(targetScreen, replayEndText, width/2-len(replayEndText)/2, 8)
+		return
+	}
+	if end.duel != nil {
+		r.printDuelResult(width, targetScreen, snapshot, end.duel)
+		return
+	}
+	restartText := fmt.Sprintf(restartMessage, r.keys.name(restartAction), r.keys.name(surrenderAction))
+	r.printLine(targetScreen, restartText, width/2-len(restartText)/2, 8)
+
+	_, height := targetScreen.Size()
+	boardBottom := height/2 + snapshot.Difficulty.ColumnCount/2*(r.verticalSpacing+1)
+	nextY := boardBottom + 2
+
+	var replayMessage string
+	if end.replaySaveError != nil {
+		replayMessage = fmt.Sprintf("The replay couldn't be saved: %s", end.replaySaveError)
+	} else if end.replayPath != "" {
+		replayMessage = fmt.Sprintf("Replay saved to %s", end.replayPath)
+	}
+	if replayMessage != "" {
+		r.printLine(targetScreen, replayMessage, width/2-len(replayMessage)/2, nextY)
+		nextY += 2
+	}
+
+	if end.scoresSaveError != nil && snapshot.Mode != engine.HotSeatMode {
+		saveErrorMessage := fmt.Sprintf("Your score couldn't be saved: %s", end.scoresSaveError)
+		r.printLine(targetScreen, saveErrorMessage, width/2-len(saveErrorMessage)/2, nextY)
+		nextY += 2
+	}
+	if end.statsSaveError != nil {
+		saveErrorMessage := fmt.Sprintf("Your statistics couldn't be saved: %s", end.statsSaveError)
+		r.printLine(targetScreen, saveErrorMessage, width/2-len(saveErrorMessage)/2, nextY)
+		nextY += 2
+	}
+	if end.adaptiveSaveError != nil {
+		saveErrorMessage := fmt.Sprintf("Your adaptive level couldn't be saved: %s", end.adaptiveSaveError)
+		r.printLine(targetScreen, saveErrorMessage, width/2-len(saveErrorMessage)/2, nextY)
+		nextY += 2
+	}
+	//Hot-seat games don't have high scores, as each score belongs to two
+	//players.
+	if snapshot.Mode == engine.HotSeatMode {
+		return
+	}
+	r.printStyledLine(targetScreen, highScoresTitle, titleStyle, width/2-len(highScoresTitle)/2, nextY)
+	r.printHighScoreTable(targetScreen, width, end.scores.get(leaderboardName(snapshot.Difficulty.VisibleName, snapshot.Mode)),
+		end.lastScore, nextY+2)
 }
 
-func (r *renderer) createInvalidKeyPressesMessage(session *gameSession) string {
-	return fmt.Sprintf("Amount of invalid key presses: %d", session.invalidKeyPresses)
+// printHotSeatResults prints which player has scored the most points and
+// the result of each player. This takes one line more than the score and
+// the invalid key presses of the other modes.
+func (r *renderer) printHotSeatResults(width int, targetScreen tcell.Screen, snapshot engine.Snapshot) {
+	leaderMessage := "Both players have scored the same amount of points."
+	if leader, hasLeader := snapshot.Leader(); hasLeader {
+		leaderMessage = fmt.Sprintf("Player %d has scored the most points!", leader+1)
+	}
+	r.printStyledLine(targetScreen, leaderMessage, titleStyle, width/2-len(leaderMessage)/2, 4)
+
+	for index, player := range snapshot.Players {
+		playerMessage := fmt.Sprintf("Player %d: %d points, %d guessed, %d invalid key presses",
+			index+1, player.Score, player.GuessedCount, player.InvalidKeyPresses)
+		if snapshot.Difficulty.SpeedBonus > 0 {
+			playerMessage = fmt.Sprintf("%s, %d speed bonus", playerMessage, player.ScoreBreakdown.SpeedBonus)
+		}
+		r.printLine(targetScreen, playerMessage, width/2-len(playerMessage)/2, 5+index)
+	}
+}
+
+// printDuelResult tells the player whether they have won the duel. As
+// duels can't be restarted, the player is told how to leave instead.
+func (r *renderer) printDuelResult(width int, targetScreen tcell.Screen, snapshot engine.Snapshot, status *duelStatus) {
+	outcome := status.outcome(snapshot)
+	if outcome == "" && status.connectionError != nil {
+		outcome = "The connection to your opponent has been lost."
+	} else if outcome == "" {
+		outcome = "Waiting for your opponent to finish ..."
+	}
+	r.printStyledLine(targetScreen, outcome, titleStyle, width/2-len(outcome)/2, 8)
+
+	leaveText := fmt.Sprintf(leaveDuelMessage, r.keys.name(surrenderAction))
+	r.printLine(targetScreen, leaveText, width/2-len(leaveText)/2, 9)
+}
+
+// printOpponentPanel prints the opponent's progress at the right edge of
+// the screen, vertically centered.
+func (r *renderer) printOpponentPanel(targetScreen tcell.Screen, snapshot engine.Snapshot, status *duelStatus) {
+	lines := []string{"Opponent"}
+	if status.opponent == nil {
+		lines = append(lines, "Waiting for progress ...")
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("Guessed: %d / %d", status.opponent.Guessed, len(snapshot.Board)),
+			fmt.Sprintf("Mistakes: %d", status.opponent.InvalidKeyPresses),
+			fmt.Sprintf("Score: %d", status.opponent.Score),
+			fmt.Sprintf("State: %s", status.opponent.State))
+	}
+	if status.connectionError != nil {
+		lines = append(lines, "Connection lost")
+	}
+
+	width, height := targetScreen.Size()
+	x := width - opponentPanelWidth
+	y := height/2 - len(lines)/2
+	for index, line := range lines {
+		style := tcell.StyleDefault
+		if index == 0 {
+			style = titleStyle
+		}
+		r.printStyledLine(targetScreen, line, style, x, y+index)
+	}
 }
 
-func (r *renderer) createScoreMessage(session *gameSession) string {
+// createEndReasonMessage explains which rule has ended the game. Ongoing
+// sessions don't have an explanation.
+func createEndReasonMessage(snapshot engine.Snapshot) string {
+	switch snapshot.EndReason {
+	case engine.TooManyHidden:
+		return fmt.Sprintf("Too many cells were hidden: %d of %d, the limit is %d.",
+			snapshot.HiddenCellCount, len(snapshot.Board), snapshot.HiddenCellLimit)
+	case engine.ScoreTooLow:
+		return fmt.Sprintf("All cells were guessed, but winning requires at least %d points.",
+			snapshot.Difficulty.MinimumWinningScore)
+	case engine.AllGuessed:
+		if snapshot.Mode == engine.PairsMode {
+			return "All pairs were found."
+		}
+		if snapshot.Mode == engine.SequenceMode {
+			return "The whole sequence was typed in the correct order."
+		}
+		return "All cells were guessed before too many were hidden."
+	case engine.Surrendered:
+		return "You surrendered."
+	}
+
+	return ""
+}
+
+func (r *renderer) createInvalidKeyPressesMessage(snapshot engine.Snapshot) string {
+	return fmt.Sprintf("Amount of invalid key presses: %d", snapshot.InvalidKeyPresses)
+}
+
+func (r *renderer) createScoreMessage(snapshot engine.Snapshot) string {
+	//In endless games there's no maximum score.
+	if snapshot.Mode == engine.EndlessMode {
+		return fmt.Sprintf("Your score is %d", snapshot.Score)
+	}
+
+	//In pairs mode, the points are given per pair.
+	if snapshot.Mode == engine.PairsMode {
+		return fmt.Sprintf("Your score is %d out of possible %d",
+			snapshot.Score, len(snapshot.Board)/2*snapshot.Difficulty.CorrectGuessPoints)
+	}
+
+	//Each guess can give the full speed bonus, if it's quick enough.
+	maximumPerGuess := snapshot.Difficulty.CorrectGuessPoints + snapshot.Difficulty.SpeedBonus
+
+	//In sequence mode, each round gives points for the whole sequence.
+	if snapshot.Mode == engine.SequenceMode {
+		cellCount := len(snapshot.Board)
+		return fmt.Sprintf("Your score is %d out of possible %d",
+			snapshot.Score, cellCount*(cellCount+1)/2*maximumPerGuess)
+	}
+
 	return fmt.Sprintf("Your score is %d out of possible %d",
-		session.score, len(session.gameBoard)*session.difficulty.correctGuessPoints)
+		snapshot.Score, len(snapshot.Board)*maximumPerGuess)
 }
 
 // printLine draws the given text at the desired position. The text will be
@@ -159,22 +786,35 @@ func (r *renderer) printStyledLine(targetScreen tcell.Screen, message string, st
 }
 
 // createScreen generates a ready to use screen. The screen has
-// no cursor and doesn't support mouse eventing.
-func createScreen() (tcell.Screen, error) {
+// no cursor and only supports mouse eventing if requested.
+func createScreen(mouse bool) (tcell.Screen, error) {
 	screen, screenCreationError := tcell.NewScreen()
 	if screenCreationError != nil {
 		return nil, screenCreationError
 	}
 
+	if screenInitError := initScreen(screen, mouse); screenInitError != nil {
+		return nil, screenInitError
+	}
+	return screen, nil
+}
+
+// initScreen initializes a newly created screen, hides the cursor and
+// enables the mouse if requested.
+func initScreen(screen tcell.Screen, mouse bool) error {
 	screenInitError := screen.Init()
 	if screenInitError != nil {
-		return nil, screenInitError
+		return screenInitError
 	}
 
-	//Make sure it's disable, even though it should be by default.
-	screen.DisableMouse()
+	if mouse {
+		screen.EnableMouse()
+	} else {
+		//Make sure it's disable, even though it should be by default.
+		screen.DisableMouse()
+	}
 	//Make sure cursor is hidden by default.
 	screen.HideCursor()
 
-	return screen, nil
+	return nil
 }
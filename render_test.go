@@ -0,0 +1,232 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestHitTesting makes sure that clicking what has been drawn leads back to
+// the cell or menu entry that has been drawn there.
+func TestHitTesting(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	renderer := newRenderer(defaultKeymap(), darkTheme)
+	for _, diff := range engine.BuiltInDifficulties() {
+		session := newTestSession(t, diff, engine.ClassicMode, 1, engine.NewManualClock(time.Time{}))
+		renderer.drawGameBoard(screen, session.Snapshot(), nil)
+
+		boardX, boardY := renderer.boardOrigin(screen, diff)
+		cells, width, _ := screen.GetContents()
+		for index, cell := range session.Snapshot().Board {
+			x := boardX + index%diff.RowCount*(renderer.horizontalSpacing+1)
+			y := boardY + index/diff.RowCount*(renderer.verticalSpacing+1)
+			if runes := cells[y*width+x].Runes; len(runes) == 0 || runes[0] != cell.Character {
+				t.Fatalf("cell %d of %s isn't drawn at %d,%d", index, diff.VisibleName, x, y)
+			}
+
+			if hitIndex, hit := renderer.cellAt(screen, diff, x, y); !hit || hitIndex != index {
+				t.Errorf("clicking %c at %d,%d on %s hit %d (%t), expected %d", cell.Character, x, y, diff.VisibleName, hitIndex, hit, index)
+			}
+			//The spacing inbetween cells belongs to the closest cell.
+			if hitIndex, hit := renderer.cellAt(screen, diff, x+1, y); !hit || hitIndex != index {
+				t.Errorf("clicking next to %c on %s hit %d (%t), expected %d", cell.Character, diff.VisibleName, hitIndex, hit, index)
+			}
+		}
+
+		if _, hit := renderer.cellAt(screen, diff, 0, 0); hit {
+			t.Errorf("clicking the corner of the screen hit a cell on %s", diff.VisibleName)
+		}
+	}
+
+	trainer, trainerError := loadAdaptiveTrainer("")
+	if trainerError != nil {
+		t.Fatal(trainerError)
+	}
+	menuState := newMenuState(darkTheme, trainer)
+	renderer.drawMenu(screen, menuState)
+	for index, entry := range menuState.entries {
+		x, y := findOnScreen(screen, entry.visibleName)
+		if hitIndex, hit := renderer.menuEntryAt(screen, menuState, x, y); !hit || hitIndex != index {
+			t.Errorf("clicking %s hit %d (%t), expected %d", entry.visibleName, hitIndex, hit, index)
+		}
+		if _, hit := renderer.menuEntryAt(screen, menuState, x, y+1); hit {
+			t.Errorf("clicking below %s hit an entry", entry.visibleName)
+		}
+	}
+}
+
+// findOnScreen returns the position at which the given text starts.
+func findOnScreen(screen tcell.SimulationScreen, text string) (int, int) {
+	cells, width, height := screen.GetContents()
+	for y := 0; y < height; y++ {
+	SEARCH:
+		for x := 0; x+len(text) <= width; x++ {
+			for offset, char := range []rune(text) {
+				if runes := cells[y*width+x+offset].Runes; len(runes) == 0 || runes[0] != char {
+					continue SEARCH
+				}
+			}
+			return x, y
+		}
+	}
+	return -1, -1
+}
+
+// TestPairsLabelsFit makes sure that the coordinate labels of pairs mode
+// are part of the required screen size, so they're neither cut off nor
+// drawn across the status lines.
+func TestPairsLabelsFit(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+
+	renderer := newRenderer(defaultKeymap(), darkTheme)
+	for _, diff := range engine.BuiltInDifficulties() {
+		session := newTestSession(t, diff, engine.PairsMode, 1, engine.NewManualClock(time.Time{}))
+		snapshot := session.Snapshot()
+		requiredWidth, requiredHeight := renderer.requiredSize(snapshot.Difficulty, snapshot.Mode, false)
+		screen.SetSize(requiredWidth, requiredHeight)
+		screen.Clear()
+		renderer.drawGameBoard(screen, snapshot, nil)
+
+		boardX, boardY := renderer.boardOrigin(screen, snapshot.Difficulty)
+		labelX, labelY := boardX-renderer.horizontalSpacing-1, boardY-1
+		if labelX < 0 || labelY < statusAreaHeight {
+			t.Errorf("the labels of %s are drawn at %d,%d", diff.VisibleName, labelX, labelY)
+			continue
+		}
+		cells, width, _ := screen.GetContents()
+		if runes := cells[labelY*width+boardX].Runes; len(runes) == 0 || runes[0] != engine.ColumnLabel(0) {
+			t.Errorf("the column labels of %s aren't drawn", diff.VisibleName)
+		}
+		if runes := cells[boardY*width+labelX].Runes; len(runes) == 0 || runes[0] != engine.RowLabel(0) {
+			t.Errorf("the row labels of %s aren't drawn", diff.VisibleName)
+		}
+	}
+}
+
+// TestOpponentPanelFits makes sure that the board and the opponent panel
+// don't overlap during duels as long as the screen is large enough.
+func TestOpponentPanelFits(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+
+	renderer := newRenderer(defaultKeymap(), darkTheme)
+	end := &endScreen{duel: &duelStatus{}}
+	for _, diff := range engine.BuiltInDifficulties() {
+		session := newTestSession(t, diff, engine.ClassicMode, 1, engine.NewManualClock(time.Time{}))
+		requiredWidth, requiredHeight := renderer.requiredSize(diff, engine.ClassicMode, true)
+		screen.SetSize(requiredWidth, requiredHeight)
+		screen.Clear()
+		renderer.drawGameBoard(screen, session.Snapshot(), end)
+
+		boardX, _ := renderer.boardOrigin(screen, diff)
+		boardRight := boardX + (diff.RowCount-1)*(renderer.horizontalSpacing+1)
+		if panelX, _ := findOnScreen(screen, "Opponent"); panelX == -1 || panelX <= boardRight {
+			t.Errorf("the opponent panel of %s is drawn at %d, but the board ends at %d", diff.VisibleName, panelX, boardRight)
+		}
+	}
+}
+
+// TestMissedCells makes sure that the cells still hidden at the end of a
+// game are revealed and annotated with their hide order.
+func TestMissedCells(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	renderer := newRenderer(defaultKeymap(), darkTheme)
+	renderer.showHideOrder = true
+	diff := findDifficulty("easy")
+	session := newTestSession(t, diff, engine.ClassicMode, 1, engine.NewManualClock(time.Time{}))
+	session.Tick()
+	session.Tick()
+	session.Surrender()
+	renderer.drawGameBoard(screen, session.Snapshot(), nil)
+
+	boardX, boardY := renderer.boardOrigin(screen, diff)
+	cells, width, _ := screen.GetContents()
+	for index, cell := range session.Snapshot().Board {
+		x := boardX + index%diff.RowCount*(renderer.horizontalSpacing+1)
+		y := boardY + index/diff.RowCount*(renderer.verticalSpacing+1)
+		drawn := cells[y*width+x]
+		if len(drawn.Runes) == 0 || drawn.Runes[0] != cell.Character {
+			t.Errorf("cell %d isn't revealed at %d,%d", index, x, y)
+		}
+
+		expectedStyle, expectedOrder := darkTheme.shown, ' '
+		if cell.State == engine.Hidden {
+			expectedStyle, expectedOrder = darkTheme.missed, rune('0'+cell.HideOrder)
+		}
+		if drawn.Style != expectedStyle {
+			t.Errorf("cell %d has an unexpected style", index)
+		}
+		if order := cells[y*width+x+1].Runes; len(order) == 0 || order[0] != expectedOrder {
+			t.Errorf("cell %d is annotated with %q, expected %q", index, order, expectedOrder)
+		}
+	}
+}
+
+// TestHotSeatResults makes sure that the end screen of a hot-seat game
+// shows the result of each player instead of the high scores.
+func TestHotSeatResults(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if initError := screen.Init(); initError != nil {
+		t.Fatal(initError)
+	}
+	defer screen.Fini()
+	screen.SetSize(100, 40)
+
+	scores, _ := loadHighScores("")
+	stats, _ := loadStats("")
+	adaptive, _ := loadAdaptiveTrainer("")
+	end := &endScreen{scores: scores, stats: stats, adaptive: adaptive}
+
+	renderer := newRenderer(defaultKeymap(), darkTheme)
+	session := newTestSession(t, findDifficulty("easy"), engine.HotSeatMode, 1, engine.NewManualClock(time.Time{}))
+	//Player 1 starts and presses a wrong key.
+	session.PressRune('x')
+	session.Surrender()
+	renderer.drawGameBoard(screen, session.Snapshot(), end)
+
+	for _, expected := range []string{
+		"Player 2 has scored the most points!",
+		"Player 1: -4 points, 0 guessed, 1 invalid key presses",
+		"Player 2: 0 points, 0 guessed, 0 invalid key presses",
+	} {
+		if x, _ := findOnScreen(screen, expected); x == -1 {
+			t.Errorf("'%s' isn't shown", expected)
+		}
+	}
+	if x, _ := findOnScreen(screen, highScoresTitle); x != -1 {
+		t.Error("the high scores are shown for a hot-seat game")
+	}
+}
+
+// newTestSession creates a session, failing the test if the difficulty
+// can't be played in the given mode.
+func newTestSession(t *testing.T, diff *engine.Difficulty, mode engine.Mode, seed int64, clock engine.Clock) *engine.Session {
+	t.Helper()
+
+	session, sessionError := engine.NewSession(diff, mode, seed, clock)
+	if sessionError != nil {
+		t.Fatal(sessionError)
+	}
+	return session
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// runReplay implements the "replay" command. It plays back a recorded
+// session in real time or faster, depending on the speed passed by the user.
+func runReplay(arguments []string, renderer *renderer) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := flags.Float64("speed", 1, "playback speed; 2 means twice as fast as the original")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: memoryalike replay [--speed factor] <file>")
+	}
+	if *speed <= 0 {
+		return errors.New("the playback speed must be greater than 0")
+	}
+
+	rec, loadError := loadRecording(flags.Arg(0))
+	if loadError != nil {
+		return loadError
+	}
+
+	session, clock, replayError := rec.NewReplaySession()
+	if replayError != nil {
+		return replayError
+	}
+	updates := session.Subscribe()
+
+	screen, screenCreationError := createScreen(false)
+	if screenCreationError != nil {
+		return screenCreationError
+	}
+	defer screen.Fini()
+
+	//Events are read on a separate goroutine, so that we can wait for the
+	//next recorded event and user input at the same time.
+	screenEvents := pollEvents(screen)
+
+	playbackStart := time.Now()
+	untilEvent := func(index int) time.Duration {
+		playbackOffset := time.Duration(float64(rec.Events[index].Offset) / *speed)
+		return playbackOffset - time.Since(playbackStart)
+	}
+	//A single timer is reset after each event, as the loop also runs for
+	//updates and user input.
+	var firstEventDelay time.Duration
+	if len(rec.Events) > 0 {
+		firstEventDelay = untilEvent(0)
+	}
+	nextEventTimer := time.NewTimer(firstEventDelay)
+	defer nextEventTimer.Stop()
+
+	nextEvent := 0
+	for {
+		renderer.drawGameBoard(screen, session.Snapshot(), nil)
+
+		var nextEventChannel <-chan time.Time
+		if nextEvent < len(rec.Events) {
+			nextEventChannel = nextEventTimer.C
+		}
+
+		select {
+		case <-nextEventChannel:
+			event := rec.Events[nextEvent]
+			clock.Advance(rec.StartTime.Add(event.Offset).Sub(clock.Now()))
+			if applyError := session.Apply(event); applyError != nil {
+				return applyError
+			}
+			nextEvent++
+			//The timer has fired, so it can be reset safely.
+			if nextEvent < len(rec.Events) {
+				nextEventTimer.Reset(untilEvent(nextEvent))
+			}
+		case _, open := <-updates:
+			//Once the session has ended, there won't be any more updates.
+			if !open {
+				updates = nil
+			}
+		case screenEvent, open := <-screenEvents:
+			if !open {
+				return nil
+			}
+			switch event := screenEvent.(type) {
+			case *tcell.EventKey:
+				if renderer.keys.is(event, surrenderAction) || renderer.keys.is(event, quitAction) {
+					return nil
+				}
+			case *tcell.EventResize:
+				screen.Clear()
+			}
+		}
+	}
+}
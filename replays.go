@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+const replaysDirectoryName = "replays"
+
+// replaysDirectory returns the directory in which replays are saved.
+func replaysDirectory() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, replaysDirectoryName), nil
+}
+
+// saveRecording writes the recording into a new file inside of the given
+// directory. The path of the written file is returned.
+func saveRecording(rec *engine.Recording, directory string) (string, error) {
+	data, marshalError := json.MarshalIndent(rec, "", "  ")
+	if marshalError != nil {
+		return "", marshalError
+	}
+
+	if mkdirError := os.MkdirAll(directory, 0755); mkdirError != nil {
+		return "", mkdirError
+	}
+
+	//Custom difficulties could contain characters that aren't allowed in
+	//file names, therefore we only keep letters and digits.
+	safeName := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '-'
+	}, rec.Difficulty.VisibleName)
+	baseName := fmt.Sprintf("%s-%s", rec.StartTime.Format("2006-01-02T15-04-05"), safeName)
+
+	//Several games can start within the same second, so the name gets a
+	//counter if the file already exists. O_EXCL makes sure that we never
+	//overwrite an existing replay.
+	for attempt := 1; ; attempt++ {
+		fileName := baseName + ".json"
+		if attempt > 1 {
+			fileName = fmt.Sprintf("%s-%d.json", baseName, attempt)
+		}
+		path := filepath.Join(directory, fileName)
+
+		file, openError := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(openError) {
+			continue
+		}
+		if openError != nil {
+			return "", openError
+		}
+
+		_, writeError := file.Write(data)
+		if closeError := file.Close(); writeError == nil {
+			writeError = closeError
+		}
+		return path, writeError
+	}
+}
+
+// loadRecording reads a recording previously written by saveRecording.
+func loadRecording(path string) (*engine.Recording, error) {
+	data, readError := ioutil.ReadFile(path)
+	if readError != nil {
+		return nil, readError
+	}
+
+	var rec engine.Recording
+	if parseError := json.Unmarshal(data, &rec); parseError != nil {
+		return nil, fmt.Errorf("error parsing replay file '%s': %w", path, parseError)
+	}
+
+	if rec.Difficulty == nil {
+		return nil, fmt.Errorf("replay file '%s' doesn't contain a difficulty", path)
+	}
+
+	return &rec, nil
+}
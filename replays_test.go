@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+// TestReplaysArentOverwritten saves the same recording twice, as if two
+// games had been started within the same second.
+func TestReplaysArentOverwritten(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	session := newTestSession(t, findDifficulty("easy"), engine.ClassicMode, 1, engine.NewManualClock(time.Time{}))
+	session.Surrender()
+
+	firstPath, firstSaveError := saveRecording(session.Recording(), tempDir)
+	if firstSaveError != nil {
+		t.Fatal(firstSaveError)
+	}
+	secondPath, secondSaveError := saveRecording(session.Recording(), tempDir)
+	if secondSaveError != nil {
+		t.Fatal(secondSaveError)
+	}
+	if firstPath == secondPath {
+		t.Fatalf("both replays have been saved to %s", firstPath)
+	}
+
+	for _, path := range []string{firstPath, secondPath} {
+		if _, loadError := loadRecording(path); loadError != nil {
+			t.Error(loadError)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/terminfo"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	//defaultSSHAddress is the address the server listens on if none has
+	//been passed.
+	defaultSSHAddress = ":2222"
+	hostKeyFileName   = "ssh_host_key"
+	//fallbackTerminal is used for players whose terminal isn't known.
+	//Pretty much every terminal emulator understands it.
+	fallbackTerminal = "xterm-256color"
+)
+
+// hostKeyPath returns the location of the key that identifies the server.
+func hostKeyPath() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, hostKeyFileName), nil
+}
+
+// loadHostKey reads the private key stored at the given path. If no file
+// exists yet, a new key is generated and saved, so that the server keeps
+// its identity across restarts.
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, readError := ioutil.ReadFile(path)
+	if readError == nil {
+		return ssh.ParsePrivateKey(data)
+	}
+	if !os.IsNotExist(readError) {
+		return nil, readError
+	}
+
+	_, key, generateError := ed25519.GenerateKey(rand.Reader)
+	if generateError != nil {
+		return nil, generateError
+	}
+	der, marshalError := x509.MarshalPKCS8PrivateKey(key)
+	if marshalError != nil {
+		return nil, marshalError
+	}
+
+	if mkdirError := os.MkdirAll(filepath.Dir(path), 0755); mkdirError != nil {
+		return nil, mkdirError
+	}
+	data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if writeError := ioutil.WriteFile(path, data, 0600); writeError != nil {
+		return nil, writeError
+	}
+
+	return ssh.NewSignerFromKey(key)
+}
+
+// loadAuthorizedKeys reads a file in the format of OpenSSH's
+// authorized_keys. The returned set contains the keys in wire format.
+func loadAuthorizedKeys(path string) (map[string]bool, error) {
+	data, readError := ioutil.ReadFile(path)
+	if readError != nil {
+		return nil, readError
+	}
+
+	authorizedKeys := make(map[string]bool)
+	for len(data) > 0 {
+		key, _, _, rest, parseError := ssh.ParseAuthorizedKey(data)
+		if parseError != nil {
+			//ParseAuthorizedKey skips comments and empty lines, so this
+			//only happens if there are no keys left.
+			if len(authorizedKeys) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("error parsing '%s': %s", path, parseError)
+		}
+		authorizedKeys[string(key.Marshal())] = true
+		data = rest
+	}
+
+	return authorizedKeys, nil
+}
+
+// newServerConfig creates the SSH configuration of the server. If no keys
+// are authorized, anyone can connect.
+func newServerConfig(hostKey ssh.Signer, authorizedKeys map[string]bool) *ssh.ServerConfig {
+	config := &ssh.ServerConfig{}
+	if authorizedKeys == nil {
+		config.NoClientAuth = true
+	} else {
+		config.PublicKeyCallback = func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if authorizedKeys[string(key.Marshal())] {
+				return nil, nil
+			}
+			return nil, errors.New("unknown public key")
+		}
+	}
+	config.AddHostKey(hostKey)
+	return config
+}
+
+// gameServer lets players play via SSH. Each player gets their own screen,
+// menu and session, but all results are added to the same high scores.
+type gameServer struct {
+	config *ssh.ServerConfig
+	//renderer is copied for each player, as players can switch the theme.
+	renderer *renderer
+	mouse    bool
+	scores   *highScoreTable
+}
+
+// serve accepts connections until the listener is closed.
+func (server *gameServer) serve(listener net.Listener) error {
+	for {
+		conn, acceptError := listener.Accept()
+		if acceptError != nil {
+			return acceptError
+		}
+		go server.handleConnection(conn)
+	}
+}
+
+// handleConnection performs the SSH handshake and starts a game for each
+// session the client opens.
+func (server *gameServer) handleConnection(conn net.Conn) {
+	serverConn, channels, requests, handshakeError := ssh.NewServerConn(conn, server.config)
+	if handshakeError != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, channelRequests, acceptError := newChannel.Accept()
+		if acceptError != nil {
+			continue
+		}
+		go server.handleSession(serverConn.User(), channel, channelRequests)
+	}
+}
+
+// ptyRequest is the payload of a "pty-req" request, see RFC 4254.
+type ptyRequest struct {
+	Term        string
+	Columns     uint32
+	Rows        uint32
+	PixelWidth  uint32
+	PixelHeight uint32
+	Modes       string
+}
+
+// windowChangeRequest is the payload of a "window-change" request.
+type windowChangeRequest struct {
+	Columns     uint32
+	Rows        uint32
+	PixelWidth  uint32
+	PixelHeight uint32
+}
+
+// handleSession plays the game once the client has requested a terminal
+// and a shell. The session is closed once the player quits.
+func (server *gameServer) handleSession(player string, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	tty := newSSHTty(channel)
+
+	//shell receives the name of the player's terminal once the game can
+	//be started. It's closed if the client goes away before that.
+	shell := make(chan string, 1)
+	go func() {
+		defer close(shell)
+		var term string
+		for request := range requests {
+			ok := false
+			switch request.Type {
+			case "pty-req":
+				var payload ptyRequest
+				if ssh.Unmarshal(request.Payload, &payload) == nil {
+					term = payload.Term
+					tty.resize(int(payload.Columns), int(payload.Rows))
+					ok = true
+				}
+			case "window-change":
+				var payload windowChangeRequest
+				if ssh.Unmarshal(request.Payload, &payload) == nil {
+					tty.resize(int(payload.Columns), int(payload.Rows))
+					ok = true
+				}
+			case "shell":
+				if term == "" {
+					fmt.Fprintln(channel.Stderr(), "memoryalike needs a terminal, try connecting via 'ssh -t'.")
+				} else if len(shell) == 0 {
+					shell <- term
+					ok = true
+				}
+			}
+			if request.WantReply {
+				request.Reply(ok, nil)
+			}
+		}
+	}()
+
+	term, open := <-shell
+	if !open {
+		return
+	}
+	status := uint32(0)
+	if playError := server.play(player, term, tty); playError != nil {
+		fmt.Fprintln(channel.Stderr(), playError)
+		status = 1
+	}
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
+
+// play blocks until the player quits or disconnects. Statistics and the
+// adaptive level only live as long as the connection.
+func (server *gameServer) play(player, term string, tty *sshTty) error {
+	screen, screenCreationError := createTerminalScreen(term, tty, server.mouse)
+	if screenCreationError != nil {
+		return screenCreationError
+	}
+	defer screen.Fini()
+
+	//Finalizing the screen closes the event channel, which ends the game
+	//loop.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-tty.disconnected:
+			screen.Fini()
+		case <-done:
+		}
+	}()
+
+	stats, _ := loadStats("")
+	adaptive, _ := loadAdaptiveTrainer("")
+	playerRenderer := *server.renderer
+	loop := &gameLoop{
+		screen:    screen,
+		events:    pollEvents(screen),
+		renderer:  &playerRenderer,
+		keys:      playerRenderer.keys,
+		menuState: newMenuState(playerRenderer.theme, adaptive),
+		end: &endScreen{
+			scores:   server.scores,
+			player:   player,
+			stats:    stats,
+			adaptive: adaptive,
+		},
+	}
+	loop.run()
+	return nil
+}
+
+// createTerminalScreen creates a screen for the given terminal. Unknown
+// terminals are treated as xterm. The capabilities of the terminal are
+// looked up directly, as tcell would otherwise use the server's $TERM.
+func createTerminalScreen(term string, tty tcell.Tty, mouse bool) (tcell.Screen, error) {
+	info, lookupError := terminfo.LookupTerminfo(term)
+	if lookupError != nil {
+		info, lookupError = terminfo.LookupTerminfo(fallbackTerminal)
+		if lookupError != nil {
+			return nil, lookupError
+		}
+	}
+
+	screen, screenCreationError := tcell.NewTerminfoScreenFromTtyTerminfo(tty, info)
+	if screenCreationError != nil {
+		return nil, screenCreationError
+	}
+
+	if screenInitError := initScreen(screen, mouse); screenInitError != nil {
+		return nil, screenInitError
+	}
+	return screen, nil
+}
+
+// sshTty allows tcell to use an SSH session as its terminal.
+type sshTty struct {
+	channel ssh.Channel
+	//input delivers everything the player types.
+	input chan []byte
+	//pending is the part of the last input that didn't fit into the
+	//buffer passed to Read.
+	pending []byte
+	//disconnected is closed once the client can't send any more input.
+	disconnected chan struct{}
+	//stopped is closed once tcell doesn't need the tty anymore.
+	stopped   chan struct{}
+	closeOnce sync.Once
+
+	mutex    sync.Mutex
+	width    int
+	height   int
+	onResize func()
+	//drained is closed by Drain, so that a blocked Read returns.
+	drained chan struct{}
+}
+
+// newSSHTty creates a tty for the given channel and starts reading input.
+func newSSHTty(channel ssh.Channel) *sshTty {
+	tty := &sshTty{
+		channel:      channel,
+		input:        make(chan []byte),
+		disconnected: make(chan struct{}),
+		stopped:      make(chan struct{}),
+		drained:      make(chan struct{}),
+	}
+	go tty.readInput()
+	return tty
+}
+
+func (tty *sshTty) readInput() {
+	defer close(tty.disconnected)
+	for {
+		buffer := make([]byte, 128)
+		n, readError := tty.channel.Read(buffer)
+		if n > 0 {
+			select {
+			case tty.input <- buffer[:n]:
+			case <-tty.stopped:
+				return
+			}
+		}
+		if readError != nil {
+			return
+		}
+	}
+}
+
+// resize sets the size of the player's terminal and notifies tcell.
+func (tty *sshTty) resize(width, height int) {
+	tty.mutex.Lock()
+	tty.width, tty.height = width, height
+	onResize := tty.onResize
+	tty.mutex.Unlock()
+
+	if onResize != nil {
+		onResize()
+	}
+}
+
+func (tty *sshTty) Start() error {
+	tty.mutex.Lock()
+	tty.drained = make(chan struct{})
+	tty.mutex.Unlock()
+	return nil
+}
+
+func (tty *sshTty) Stop() error {
+	return nil
+}
+
+func (tty *sshTty) Drain() error {
+	tty.mutex.Lock()
+	defer tty.mutex.Unlock()
+
+	select {
+	case <-tty.drained:
+	default:
+		close(tty.drained)
+	}
+	return nil
+}
+
+func (tty *sshTty) NotifyResize(onResize func()) {
+	tty.mutex.Lock()
+	tty.onResize = onResize
+	tty.mutex.Unlock()
+}
+
+func (tty *sshTty) WindowSize() (int, int, error) {
+	tty.mutex.Lock()
+	defer tty.mutex.Unlock()
+
+	return tty.width, tty.height, nil
+}
+
+func (tty *sshTty) Read(data []byte) (int, error) {
+	tty.mutex.Lock()
+	drained := tty.drained
+	tty.mutex.Unlock()
+
+	if len(tty.pending) == 0 {
+		select {
+		case tty.pending = <-tty.input:
+		case <-tty.disconnected:
+			return 0, io.EOF
+		case <-drained:
+			return 0, nil
+		}
+	}
+
+	n := copy(data, tty.pending)
+	tty.pending = tty.pending[n:]
+	return n, nil
+}
+
+func (tty *sshTty) Write(data []byte) (int, error) {
+	return tty.channel.Write(data)
+}
+
+// Close stops reading input. The channel itself is closed once the game
+// has ended, as the exit status still has to be sent.
+func (tty *sshTty) Close() error {
+	tty.closeOnce.Do(func() {
+		close(tty.stopped)
+	})
+	return nil
+}
+
+// runServe implements the "serve" command, which lets players connect via
+// SSH and play on their own, while sharing the high scores.
+func runServe(arguments []string, renderer *renderer, mouse bool) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	address := flags.String("ssh", defaultSSHAddress, "address the SSH server listens on")
+	defaultHostKeyPath, _ := hostKeyPath()
+	hostKeyFile := flags.String("host-key", defaultHostKeyPath, "private key identifying the server; generated if missing")
+	authorizedKeysFile := flags.String("authorized-keys", "", "file containing the public keys allowed to connect; anyone can connect by default")
+	flags.Parse(arguments)
+
+	if flags.NArg() > 0 {
+		return errors.New("usage: memoryalike serve [--ssh address] [--host-key file] [--authorized-keys file]")
+	}
+	if *hostKeyFile == "" {
+		return errors.New("no host key file passed and the config directory couldn't be determined")
+	}
+
+	hostKey, hostKeyError := loadHostKey(*hostKeyFile)
+	if hostKeyError != nil {
+		return fmt.Errorf("error loading host key from '%s': %s", *hostKeyFile, hostKeyError)
+	}
+	var authorizedKeys map[string]bool
+	if *authorizedKeysFile != "" {
+		var authorizedKeysError error
+		authorizedKeys, authorizedKeysError = loadAuthorizedKeys(*authorizedKeysFile)
+		if authorizedKeysError != nil {
+			return authorizedKeysError
+		}
+	}
+
+	scoresPath, _ := highScoresPath()
+	scores, scoresError := loadHighScores(scoresPath)
+	if scoresError != nil {
+		return fmt.Errorf("error loading high scores from '%s': %s", scoresPath, scoresError)
+	}
+
+	listener, listenError := net.Listen("tcp", *address)
+	if listenError != nil {
+		return listenError
+	}
+	defer listener.Close()
+
+	fmt.Printf("Players can connect via 'ssh -p <port> <host>', listening on %s ...\n", listener.Addr())
+	server := &gameServer{
+		config:   newServerConfig(hostKey, authorizedKeys),
+		renderer: renderer,
+		mouse:    mouse,
+		scores:   scores,
+	}
+	return server.serve(listener)
+}
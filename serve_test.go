@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHostKey(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "nested", hostKeyFileName)
+	generated, generateError := loadHostKey(path)
+	if generateError != nil {
+		t.Fatal(generateError)
+	}
+	loaded, loadError := loadHostKey(path)
+	if loadError != nil {
+		t.Fatal(loadError)
+	}
+	if !bytes.Equal(generated.PublicKey().Marshal(), loaded.PublicKey().Marshal()) {
+		t.Error("the host key changed after reloading it")
+	}
+}
+
+// syncBuffer collects the output of an SSH session, which is written by
+// another goroutine.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(data)
+}
+
+func (b *syncBuffer) contains(text string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return strings.Contains(b.buffer.String(), text)
+}
+
+// TestServeViaSSH plays a game via SSH and makes sure the result ends up
+// in the shared high scores. Clients with unknown keys are rejected.
+func TestServeViaSSH(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	hostKey, hostKeyError := loadHostKey(filepath.Join(tempDir, hostKeyFileName))
+	if hostKeyError != nil {
+		t.Fatal(hostKeyError)
+	}
+	newClientKey := func() ssh.Signer {
+		_, key, generateError := ed25519.GenerateKey(rand.Reader)
+		if generateError != nil {
+			t.Fatal(generateError)
+		}
+		signer, signerError := ssh.NewSignerFromKey(key)
+		if signerError != nil {
+			t.Fatal(signerError)
+		}
+		return signer
+	}
+	clientKey, unknownKey := newClientKey(), newClientKey()
+
+	authorizedKeysPath := filepath.Join(tempDir, "authorized_keys")
+	authorizedKeysData := append([]byte("# the team\n"), ssh.MarshalAuthorizedKey(clientKey.PublicKey())...)
+	if writeError := ioutil.WriteFile(authorizedKeysPath, authorizedKeysData, 0644); writeError != nil {
+		t.Fatal(writeError)
+	}
+	authorizedKeys, authorizedKeysError := loadAuthorizedKeys(authorizedKeysPath)
+	if authorizedKeysError != nil {
+		t.Fatal(authorizedKeysError)
+	}
+
+	listener, listenError := net.Listen("tcp", "127.0.0.1:0")
+	if listenError != nil {
+		t.Fatal(listenError)
+	}
+	defer listener.Close()
+	scores, _ := loadHighScores("")
+	server := &gameServer{
+		config:   newServerConfig(hostKey, authorizedKeys),
+		renderer: newRenderer(defaultKeymap(), darkTheme),
+		scores:   scores,
+	}
+	go server.serve(listener)
+
+	dial := func(key ssh.Signer) (*ssh.Client, error) {
+		return ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+			User:            "alice",
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(key)},
+			HostKeyCallback: ssh.FixedHostKey(hostKey.PublicKey()),
+			Timeout:         5 * time.Second,
+		})
+	}
+	if client, dialError := dial(unknownKey); dialError == nil {
+		client.Close()
+		t.Fatal("a client with an unknown key was allowed to connect")
+	}
+
+	client, dialError := dial(clientKey)
+	if dialError != nil {
+		t.Fatal(dialError)
+	}
+	defer client.Close()
+	session, sessionError := client.NewSession()
+	if sessionError != nil {
+		t.Fatal(sessionError)
+	}
+	defer session.Close()
+
+	output := &syncBuffer{}
+	session.Stdout = output
+	input, inputError := session.StdinPipe()
+	if inputError != nil {
+		t.Fatal(inputError)
+	}
+	if ptyError := session.RequestPty("xterm", 40, 100, ssh.TerminalModes{}); ptyError != nil {
+		t.Fatal(ptyError)
+	}
+	if shellError := session.Shell(); shellError != nil {
+		t.Fatal(shellError)
+	}
+
+	waitFor := func(text string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !output.contains(text) {
+			if time.Now().After(deadline) {
+				t.Fatalf("'%s' wasn't shown", text)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	//Starts "normal", surrenders and quits.
+	waitFor("normal")
+	input.Write([]byte("\r"))
+	waitFor("Score:")
+	input.Write([]byte{0x1b})
+	waitFor("GAME")
+	input.Write([]byte{0x03})
+	if waitError := session.Wait(); waitError != nil {
+		t.Fatal(waitError)
+	}
+
+	entries := scores.get("normal")
+	if len(entries) != 1 || entries[0].Player != "alice" {
+		t.Errorf("the game wasn't added to the shared high scores: %+v", entries)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+const (
+	statsFileName = "stats.json"
+	//maxListedCharacters limits the lists of missed characters and wrong
+	//keys to the most frequent ones.
+	maxListedCharacters = 5
+)
+
+// difficultyStats sums up all games played on a single difficulty and mode.
+type difficultyStats struct {
+	Played int `json:"played"`
+	Won    int `json:"won"`
+	//TotalReactionTime is the sum of the time it took to guess each of the
+	//Guesses hidden cells.
+	TotalReactionTime time.Duration `json:"totalReactionTime"`
+	Guesses           int           `json:"guesses"`
+}
+
+// averageReactionTime returns the average time between hiding and guessing
+// a cell. If no cell has been guessed yet, 0 is returned.
+func (stats *difficultyStats) averageReactionTime() time.Duration {
+	if stats.Guesses == 0 {
+		return 0
+	}
+
+	return stats.TotalReactionTime / time.Duration(stats.Guesses)
+}
+
+// playerStats is the persistent summary of all games that have been
+// played. Unlike the high scores, it never forgets a game.
+type playerStats struct {
+	path string
+
+	//Difficulties is keyed by the same names as the high scores, so
+	//each mode is tracked separately.
+	Difficulties map[string]*difficultyStats `json:"difficulties"`
+	//MissedCharacters counts how often each character was still hidden
+	//once a game was over.
+	MissedCharacters map[string]int `json:"missedCharacters"`
+	//WrongKeys counts how often each key was pressed by mistake.
+	WrongKeys map[string]int `json:"wrongKeys"`
+}
+
+// characterCount is a single entry of a list of characters sorted by how
+// often they occurred.
+type characterCount struct {
+	Character string `json:"character"`
+	Count     int    `json:"count"`
+}
+
+// statsPath returns the location of the statistics file.
+func statsPath() (string, error) {
+	configDir, configDirError := configDirectory()
+	if configDirError != nil {
+		return "", configDirError
+	}
+
+	return filepath.Join(configDir, statsFileName), nil
+}
+
+// loadStats reads the statistics stored at the given path. If no file
+// exists yet, empty statistics are returned. An empty path results in
+// statistics that only live in memory.
+func loadStats(path string) (*playerStats, error) {
+	stats := &playerStats{
+		path:             path,
+		Difficulties:     make(map[string]*difficultyStats),
+		MissedCharacters: make(map[string]int),
+		WrongKeys:        make(map[string]int),
+	}
+
+	if path == "" {
+		return stats, nil
+	}
+
+	data, readError := ioutil.ReadFile(path)
+	if readError != nil {
+		if os.IsNotExist(readError) {
+			return stats, nil
+		}
+		return nil, readError
+	}
+
+	if parseError := json.Unmarshal(data, stats); parseError != nil {
+		return nil, parseError
+	}
+	//Keys set to null in the file would leave us with nil maps.
+	if stats.Difficulties == nil {
+		stats.Difficulties = make(map[string]*difficultyStats)
+	}
+	if stats.MissedCharacters == nil {
+		stats.MissedCharacters = make(map[string]int)
+	}
+	if stats.WrongKeys == nil {
+		stats.WrongKeys = make(map[string]int)
+	}
+	//Edited or corrupted files might contain entries that can't result
+	//from playing. Difficulties without games are dropped, as they'd
+	//have no win rate.
+	for name, difficulty := range stats.Difficulties {
+		if difficulty == nil || difficulty.Played <= 0 {
+			delete(stats.Difficulties, name)
+			continue
+		}
+		if difficulty.Won < 0 {
+			difficulty.Won = 0
+		} else if difficulty.Won > difficulty.Played {
+			difficulty.Won = difficulty.Played
+		}
+	}
+
+	return stats, nil
+}
+
+// add includes the result of the given session. The session should already
+// be over. The statistics aren't saved automatically.
+func (stats *playerStats) add(snapshot engine.Snapshot) {
+	name := leaderboardName(snapshot.Difficulty.VisibleName, snapshot.Mode)
+	difficulty, known := stats.Difficulties[name]
+	if !known {
+		difficulty = &difficultyStats{}
+		stats.Difficulties[name] = difficulty
+	}
+
+	difficulty.Played++
+	if snapshot.State == engine.Victory {
+		difficulty.Won++
+	}
+	for _, reactionTime := range snapshot.ReactionTimes {
+		difficulty.TotalReactionTime += reactionTime
+		difficulty.Guesses++
+	}
+
+	for _, cell := range snapshot.Board {
+		if cell.State == engine.Hidden {
+			stats.MissedCharacters[string(cell.Character)]++
+		}
+	}
+	for key, count := range snapshot.WrongKeys {
+		stats.WrongKeys[string(key)] += count
+	}
+}
+
+// mostFrequent returns the characters that occurred most often, sorted by
+// their count in descending order. Ties are sorted alphabetically, so that
+// the order is stable.
+func mostFrequent(counts map[string]int) []characterCount {
+	sorted := make([]characterCount, 0, len(counts))
+	for character, count := range counts {
+		sorted = append(sorted, characterCount{character, count})
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a].Count != sorted[b].Count {
+			return sorted[a].Count > sorted[b].Count
+		}
+		return sorted[a].Character < sorted[b].Character
+	})
+
+	if len(sorted) > maxListedCharacters {
+		sorted = sorted[:maxListedCharacters]
+	}
+	return sorted
+}
+
+// difficultyNames returns the names of all difficulties that have been
+// played, sorted alphabetically.
+func (stats *playerStats) difficultyNames() []string {
+	names := make([]string, 0, len(stats.Difficulties))
+	for name := range stats.Difficulties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lines formats the statistics as human readable text, one line per
+// element. It's used by both the stats screen and the stats command.
+func (stats *playerStats) lines() []string {
+	if len(stats.Difficulties) == 0 {
+		return []string{"No games have been played yet."}
+	}
+
+	lines := []string{fmt.Sprintf("%-24s %6s %6s %9s %14s", "Difficulty", "Played", "Won", "Win rate", "Reaction time")}
+	for _, name := range stats.difficultyNames() {
+		difficulty := stats.Difficulties[name]
+		reactionTime := "-"
+		if difficulty.Guesses > 0 {
+			reactionTime = difficulty.averageReactionTime().Round(time.Millisecond).String()
+		}
+		winRate := "-"
+		if difficulty.Played > 0 {
+			winRate = fmt.Sprintf("%d%%", difficulty.Won*100/difficulty.Played)
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %6d %6d %9s %14s", name, difficulty.Played, difficulty.Won,
+			winRate, reactionTime))
+	}
+
+	formatCounts := func(title string, counts map[string]int) string {
+		entries := mostFrequent(counts)
+		if len(entries) == 0 {
+			return title + ": none"
+		}
+
+		formatted := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			formatted = append(formatted, fmt.Sprintf("%s (%d)", entry.Character, entry.Count))
+		}
+		return title + ": " + strings.Join(formatted, ", ")
+	}
+
+	return append(lines, "",
+		formatCounts("Most missed characters", stats.MissedCharacters),
+		formatCounts("Most common wrong keys", stats.WrongKeys))
+}
+
+// statsReport is the JSON representation printed by the stats command. In
+// contrast to the file, it contains the derived values as well.
+type statsReport struct {
+	Difficulties     map[string]difficultyReport `json:"difficulties"`
+	MissedCharacters []characterCount            `json:"missedCharacters"`
+	WrongKeys        []characterCount            `json:"wrongKeys"`
+}
+
+type difficultyReport struct {
+	Played int `json:"played"`
+	Won    int `json:"won"`
+	//AverageReactionTime is given in milliseconds.
+	AverageReactionTime int64 `json:"averageReactionTime"`
+}
+
+// writeJSON writes the statistics including all derived values.
+func (stats *playerStats) writeJSON(target io.Writer) error {
+	report := statsReport{
+		Difficulties:     make(map[string]difficultyReport, len(stats.Difficulties)),
+		MissedCharacters: mostFrequent(stats.MissedCharacters),
+		WrongKeys:        mostFrequent(stats.WrongKeys),
+	}
+	for name, difficulty := range stats.Difficulties {
+		report.Difficulties[name] = difficultyReport{
+			Played:              difficulty.Played,
+			Won:                 difficulty.Won,
+			AverageReactionTime: difficulty.averageReactionTime().Milliseconds(),
+		}
+	}
+
+	encoder := json.NewEncoder(target)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// save writes the statistics to disk, creating the config directory if
+// required.
+func (stats *playerStats) save() error {
+	if stats.path == "" {
+		return nil
+	}
+
+	data, marshalError := json.MarshalIndent(stats, "", "  ")
+	if marshalError != nil {
+		return marshalError
+	}
+
+	if mkdirError := os.MkdirAll(filepath.Dir(stats.path), 0755); mkdirError != nil {
+		return mkdirError
+	}
+
+	return ioutil.WriteFile(stats.path, data, 0644)
+}
+
+// runStats implements the "stats" command, which prints the statistics
+// either as text or as JSON.
+func runStats(arguments []string, target io.Writer) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "prints the statistics as JSON")
+	flags.Parse(arguments)
+
+	if flags.NArg() != 0 {
+		return errors.New("usage: memoryalike stats [--json]")
+	}
+
+	path, pathError := statsPath()
+	if pathError != nil {
+		return pathError
+	}
+	stats, loadError := loadStats(path)
+	if loadError != nil {
+		return fmt.Errorf("error loading statistics from '%s': %w", path, loadError)
+	}
+
+	if *asJSON {
+		return stats.writeJSON(target)
+	}
+
+	for _, line := range stats.lines() {
+		if _, writeError := fmt.Fprintln(target, line); writeError != nil {
+			return writeError
+		}
+	}
+	return nil
+}
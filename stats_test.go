@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bios-Marcel/memoryalike/engine"
+)
+
+func TestPlayerStats(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "nested", statsFileName)
+	stats, loadError := loadStats(path)
+	if loadError != nil {
+		t.Fatal(loadError)
+	}
+	if lines := stats.lines(); len(lines) != 1 {
+		t.Errorf("expected a single line for empty statistics, got %v", lines)
+	}
+
+	//One game is surrendered with two cells still hidden, after having
+	//guessed one cell and pressed two wrong keys.
+	clock := engine.NewManualClock(time.Time{})
+	lost := newTestSession(t, findDifficulty("easy"), engine.ClassicMode, 1, clock)
+	lost.Tick()
+	clock.Advance(300 * time.Millisecond)
+	lost.PressRune(lost.Snapshot().Board[hiddenIndex(lost.Snapshot())].Character)
+	lost.Tick()
+	lost.Tick()
+	lost.PressRune('x')
+	lost.PressRune('x')
+	lost.Surrender()
+	stats.add(lost.Snapshot())
+
+	won := engine.Snapshot{State: engine.Victory, Difficulty: findDifficulty("easy"),
+		ReactionTimes: []time.Duration{100 * time.Millisecond}}
+	stats.add(won)
+
+	if saveError := stats.save(); saveError != nil {
+		t.Fatal(saveError)
+	}
+	reloaded, reloadError := loadStats(path)
+	if reloadError != nil {
+		t.Fatal(reloadError)
+	}
+
+	easy := reloaded.Difficulties["easy"]
+	if easy == nil || easy.Played != 2 || easy.Won != 1 || easy.averageReactionTime() != 200*time.Millisecond {
+		t.Fatalf("unexpected statistics for easy: %+v", easy)
+	}
+	if missed := mostFrequent(reloaded.MissedCharacters); len(missed) != 2 {
+		t.Errorf("expected two missed characters, got %v", missed)
+	}
+	if wrongKeys := mostFrequent(reloaded.WrongKeys); len(wrongKeys) != 1 || wrongKeys[0] != (characterCount{"x", 2}) {
+		t.Errorf("expected x to be pressed wrongly twice, got %v", wrongKeys)
+	}
+
+	text := strings.Join(reloaded.lines(), "\n")
+	for _, expected := range []string{"easy", "50%", "200ms", "Most common wrong keys: x (2)"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("text '%s' doesn't contain '%s'", text, expected)
+		}
+	}
+
+	var output bytes.Buffer
+	if writeError := reloaded.writeJSON(&output); writeError != nil {
+		t.Fatal(writeError)
+	}
+	var report statsReport
+	if parseError := json.Unmarshal(output.Bytes(), &report); parseError != nil {
+		t.Fatal(parseError)
+	}
+	if report.Difficulties["easy"].AverageReactionTime != 200 || len(report.WrongKeys) != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+// TestImpossibleStatsAreFixed makes sure that statistics which can't
+// result from playing, e.g. because the file has been edited by hand, don't
+// break the stats screen.
+func TestImpossibleStatsAreFixed(t *testing.T) {
+	tempDir, tempDirError := ioutil.TempDir("", "memoryalike")
+	if tempDirError != nil {
+		t.Fatal(tempDirError)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, statsFileName)
+	data := []byte(`{"difficulties": {"easy": {"played": 0, "won": 0}, "normal": {"played": 2, "won": 5}, "hard": null}}`)
+	if writeError := ioutil.WriteFile(path, data, 0644); writeError != nil {
+		t.Fatal(writeError)
+	}
+	stats, loadError := loadStats(path)
+	if loadError != nil {
+		t.Fatal(loadError)
+	}
+
+	if names := stats.difficultyNames(); len(names) != 1 || names[0] != "normal" {
+		t.Errorf("expected only normal to be kept, got %v", names)
+	}
+	if normal := stats.Difficulties["normal"]; normal.Won != 2 {
+		t.Errorf("expected the games won to be limited to the games played, got %d", normal.Won)
+	}
+	if text := strings.Join(stats.lines(), "\n"); !strings.Contains(text, "100%") {
+		t.Errorf("text '%s' doesn't contain the win rate", text)
+	}
+}
+
+// hiddenIndex returns the index of the first hidden cell.
+func hiddenIndex(snapshot engine.Snapshot) int {
+	for index, cell := range snapshot.Board {
+		if cell.State == engine.Hidden {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// minimumThemeColors is the amount of colors a terminal has to support for
+// the colored themes to be used. Terminals with less colors fall back to
+// monochromeTheme.
+const minimumThemeColors = 8
+
+// theme defines the styles used for drawing the cells of the board, one
+// per state a cell can be in.
+type theme struct {
+	name    string
+	shown   tcell.Style
+	hidden  tcell.Style
+	guessed tcell.Style
+	//revealed is used for the cells that are currently turned over in
+	//pairs mode.
+	revealed tcell.Style
+	//missed is used for the cells that were still hidden when the game
+	//ended.
+	missed tcell.Style
+}
+
+var (
+	darkTheme = &theme{
+		name:     "dark",
+		shown:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		hidden:   tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorBlack),
+		guessed:  tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack),
+		revealed: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow),
+		missed:   tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack),
+	}
+	lightTheme = &theme{
+		name:     "light",
+		shown:    tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		hidden:   tcell.StyleDefault.Foreground(tcell.ColorDarkGray).Background(tcell.ColorWhite),
+		guessed:  tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorWhite),
+		revealed: tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorNavy),
+		missed:   tcell.StyleDefault.Foreground(tcell.ColorMaroon).Background(tcell.ColorWhite),
+	}
+	highContrastTheme = &theme{
+		name:     "high-contrast",
+		shown:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Bold(true),
+		hidden:   tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack),
+		guessed:  tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite).Bold(true),
+		revealed: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow).Bold(true),
+		missed:   tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorRed).Bold(true),
+	}
+	//colorblindSafeTheme uses the Okabe-Ito palette, which avoids telling
+	//states apart by red and green only.
+	colorblindSafeTheme = &theme{
+		name:     "colorblind-safe",
+		shown:    tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		hidden:   tcell.StyleDefault.Foreground(tcell.NewRGBColor(0x99, 0x99, 0x99)).Background(tcell.ColorBlack),
+		guessed:  tcell.StyleDefault.Foreground(tcell.NewRGBColor(0x56, 0xB4, 0xE9)).Background(tcell.ColorBlack),
+		revealed: tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.NewRGBColor(0xF0, 0xE4, 0x42)),
+		missed:   tcell.StyleDefault.Foreground(tcell.NewRGBColor(0xE6, 0x9F, 0x00)).Background(tcell.ColorBlack),
+	}
+	//monochromeTheme only relies on text attributes. It's used whenever
+	//the terminal doesn't support enough colors.
+	monochromeTheme = &theme{
+		name:     "monochrome",
+		shown:    tcell.StyleDefault,
+		hidden:   tcell.StyleDefault,
+		guessed:  tcell.StyleDefault.Bold(true),
+		revealed: tcell.StyleDefault.Underline(true),
+		missed:   tcell.StyleDefault.Reverse(true),
+	}
+)
+
+// themes contains all themes that can be chosen by the player. The first
+// one is used by default.
+var themes = []*theme{darkTheme, lightTheme, highContrastTheme, colorblindSafeTheme}
+
+// findTheme returns the theme with the given name, ignoring the case.
+func findTheme(name string) (*theme, error) {
+	names := make([]string, 0, len(themes))
+	for _, candidate := range themes {
+		if strings.EqualFold(candidate.name, name) {
+			return candidate, nil
+		}
+		names = append(names, candidate.name)
+	}
+
+	return nil, fmt.Errorf("unknown theme '%s'; available themes are %s", name, strings.Join(names, ", "))
+}
+
+// nextTheme returns the theme following the given one, wrapping around at
+// the end.
+func nextTheme(current *theme) *theme {
+	for index, candidate := range themes {
+		if candidate == current {
+			return themes[(index+1)%len(themes)]
+		}
+	}
+
+	return themes[0]
+}
+
+// effectiveTheme returns the given theme, unless the screen can't display
+// enough colors, in which case the monochrome fallback is returned.
+func effectiveTheme(targetScreen tcell.Screen, chosen *theme) *theme {
+	if targetScreen.Colors() < minimumThemeColors {
+		return monochromeTheme
+	}
+
+	return chosen
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// monochromeScreen is a simulation screen that doesn't support colors.
+type monochromeScreen struct {
+	tcell.SimulationScreen
+}
+
+func (monochromeScreen) Colors() int {
+	return 0
+}
+
+func TestThemes(t *testing.T) {
+	for _, name := range []string{"dark", "Light", "HIGH-CONTRAST", "colorblind-safe"} {
+		if _, findError := findTheme(name); findError != nil {
+			t.Error(findError)
+		}
+	}
+	if _, findError := findTheme("monochrome"); findError == nil {
+		t.Error("the monochrome fallback shouldn't be selectable")
+	}
+
+	//Cycling through the themes ends up at the first one again.
+	current := themes[0]
+	for range themes {
+		current = nextTheme(current)
+	}
+	if current != themes[0] {
+		t.Errorf("expected to end up at %s, got %s", themes[0].name, current.name)
+	}
+
+	//All states have to be distinguishable in every theme.
+	for _, candidate := range append(themes, monochromeTheme) {
+		styles := []tcell.Style{candidate.shown, candidate.guessed, candidate.revealed, candidate.missed}
+		for first := range styles {
+			for second := first + 1; second < len(styles); second++ {
+				if styles[first] == styles[second] {
+					t.Errorf("%s uses the same style for states %d and %d", candidate.name, first, second)
+				}
+			}
+		}
+	}
+
+	screen := tcell.NewSimulationScreen("")
+	if effectiveTheme(screen, lightTheme) != lightTheme {
+		t.Error("expected the chosen theme on a screen with colors")
+	}
+	if effectiveTheme(monochromeScreen{screen}, lightTheme) != monochromeTheme {
+		t.Error("expected the monochrome theme on a screen without colors")
+	}
+}